@@ -1,17 +1,38 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"kubeforge/internal/k8s/controller"
+	"kubeforge/internal/k8s/diff"
+	"kubeforge/internal/k8s/render"
 	"kubeforge/pkg/signals"
 	"net/http"
+	"os"
 	"sync"
+	"time"
 
   "github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+	kubeforgeYaml "kubeforge/internal/ops/yaml"
+	crdClientSet "kubeforge/pkg/generated/clientset/versioned"
+
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
 )
 
 // Health state variables
@@ -70,6 +91,193 @@ func setHealhtz(state bool) {
 	isHealthy = state
 }
 
+// readOverlays reads every Overlay of the overlay file, overlays without
+// namespace get the given one.
+func readOverlays(overlayPath, namespace string) ([]crdv1.Overlay, error) {
+
+  overlayFile, err := os.Open(overlayPath)
+  if err != nil {
+    return nil, fmt.Errorf("failed to open overlay file: %w", err)
+  }
+  defer overlayFile.Close()
+
+  crdOverlays := []crdv1.Overlay{}
+  decoder := utilyaml.NewYAMLOrJSONDecoder(overlayFile, 4096)
+  for {
+    var crdOverlay crdv1.Overlay
+    if err := decoder.Decode(&crdOverlay); err == io.EOF {
+      return crdOverlays, nil
+    } else if err != nil {
+      return nil, fmt.Errorf("failed to decode overlay file: %w", err)
+    }
+
+    // Skip empty documents
+    if crdOverlay.Kind == "" {
+      continue
+    }
+    if crdOverlay.Kind != "Overlay" {
+      return nil, fmt.Errorf("unexpected kind %q in overlay file", crdOverlay.Kind)
+    }
+    if crdOverlay.Namespace == "" {
+      crdOverlay.Namespace = namespace
+    }
+    crdOverlays = append(crdOverlays, crdOverlay)
+  }
+}
+
+// fileOverlays returns a render.OverlayGetter reading the base overlays out
+// of the overlay file, the fallback is used for the ones not in the file.
+func fileOverlays(crdOverlays []crdv1.Overlay, fallback render.OverlayGetter) render.OverlayGetter {
+  return func(namespace, name string) (*crdv1.Overlay, error) {
+    for index := range crdOverlays {
+      if crdOverlays[index].Namespace == namespace && crdOverlays[index].Name == name {
+        return &crdOverlays[index], nil
+      }
+    }
+    if fallback != nil {
+      return fallback(namespace, name)
+    }
+    return nil, fmt.Errorf("overlay %q not found in the overlay file", name)
+  }
+}
+
+// renderOverlays renders every Overlay of the overlay file on top of the
+// source configuration and writes the resources as multi-document YAML.
+func renderOverlays(ctx context.Context, out io.Writer, sourcePath, overlayPath, namespace string, environment render.Environment) error {
+
+  crdOverlays, err := readOverlays(overlayPath, namespace)
+  if err != nil {
+    return err
+  }
+
+  resolver := render.SchemeResolver()
+  for _, crdOverlay := range crdOverlays {
+    if crdOverlay.Spec.Abstract {
+      continue
+    }
+    resources, err := render.Render(ctx, sourcePath, &crdOverlay, resolver, render.SchemeSchemas(resolver), environment, fileOverlays(crdOverlays, nil))
+    if err != nil {
+      return fmt.Errorf("failed to render overlay %q: %w", crdOverlay.Name, err)
+    }
+
+    for _, resource := range resources {
+      resourceYAML, err := yaml.Marshal(resource.Object.Object)
+      if err != nil {
+        return err
+      }
+      fmt.Fprintf(out, "---\n%s", resourceYAML)
+    }
+  }
+  return nil
+}
+
+// diffOverlays renders the overlays, read from the overlay file or from the
+// cluster by name, and prints what the controller would change for them.
+func diffOverlays(
+  ctx               context.Context,
+  out               io.Writer,
+  kubernetesConfig  string,
+  kubernetesAddress string,
+  controllerName    string,
+  sourcePath        string,
+  overlayPath       string,
+  overlayName       string,
+  namespace         string,
+  environment       render.Environment,
+) error {
+
+  if (overlayPath == "") == (overlayName == "") {
+    return fmt.Errorf("exactly one of --overlay or --name must be set")
+  }
+
+  connectionConfig, err := clientcmd.BuildConfigFromFlags(kubernetesAddress, kubernetesConfig)
+  if err != nil {
+    return fmt.Errorf("failed to setup building Kubernetes connection object: %w", err)
+  }
+  k8sClient, err := kubernetes.NewForConfig(connectionConfig)
+  if err != nil {
+    return fmt.Errorf("failed to create Kubernetes client: %w", err)
+  }
+  dynClient, err := dynamic.NewForConfig(connectionConfig)
+  if err != nil {
+    return fmt.Errorf("failed to create dynamic client: %w", err)
+  }
+  crdClient, err := crdClientSet.NewForConfig(connectionConfig)
+  if err != nil {
+    return fmt.Errorf("failed to create CRD client: %w", err)
+  }
+
+  // Load the overlays
+  var crdOverlays []crdv1.Overlay
+  if overlayPath != "" {
+    crdOverlays, err = readOverlays(overlayPath, namespace)
+    if err != nil {
+      return err
+    }
+  } else {
+    crdOverlays = []crdv1.Overlay{{ObjectMeta: metav1.ObjectMeta{Name: overlayName, Namespace: namespace}}}
+  }
+
+  resourceMapper := controllerMisc.NewResourceMapper(k8sClient.Discovery())
+  mergeSchemas := controllerMisc.NewMergeSchemas(resourceMapper, k8sClient.Discovery())
+
+  // Base overlays are read from the overlay file, then from the cluster
+  getOverlay := func(namespace, name string) (*crdv1.Overlay, error) {
+    return crdClient.KubeforgeV1().Overlays(namespace).Get(ctx, name, metav1.GetOptions{})
+  }
+  if overlayPath != "" {
+    getOverlay = fileOverlays(crdOverlays, getOverlay)
+  }
+
+  for _, crdOverlay := range crdOverlays {
+
+    // The live overlay provides the inventory and the owner UID
+    liveOverlay, err := crdClient.KubeforgeV1().Overlays(crdOverlay.Namespace).Get(ctx, crdOverlay.Name, metav1.GetOptions{})
+    switch {
+    case err == nil && overlayPath == "":
+      crdOverlay = *liveOverlay
+    case err == nil:
+      crdOverlay.UID = liveOverlay.UID
+      crdOverlay.Status = liveOverlay.Status
+    case !errors.IsNotFound(err) || overlayPath == "":
+      return fmt.Errorf("failed to get overlay %q: %w", crdOverlay.Name, err)
+    }
+    crdOverlay.SetGroupVersionKind(crdv1.SchemeGroupVersion.WithKind("Overlay"))
+
+    // Abstract overlays are not applied, there is nothing to diff
+    if crdOverlay.Spec.Abstract {
+      continue
+    }
+
+    // Render on top of the referenced OverlaySource, as the controller does
+    sourceConfiguration := sourcePath
+    if crdOverlay.Spec.SourceRef != nil {
+      crdSource, err := crdClient.KubeforgeV1().OverlaySources(crdOverlay.Namespace).Get(ctx, crdOverlay.Spec.SourceRef.Name, metav1.GetOptions{})
+      if err != nil {
+        return fmt.Errorf("failed to get overlay source %q: %w", crdOverlay.Spec.SourceRef.Name, err)
+      }
+      sourceConfiguration = string(crdSource.Spec.Data.Raw)
+    }
+
+    resources, err := render.Render(ctx, sourceConfiguration, &crdOverlay, resourceMapper.MappingFor, mergeSchemas.For, environment, getOverlay)
+    if err != nil {
+      return fmt.Errorf("failed to render overlay %q: %w", crdOverlay.Name, err)
+    }
+
+    results, err := diff.Resources(ctx, dynClient, controllerName, crdOverlay.Spec.DriftPolicy, mergeSchemas, resources)
+    if err != nil {
+      return fmt.Errorf("failed to diff overlay %q: %w", crdOverlay.Name, err)
+    }
+    pruned, err := diff.Pruned(ctx, dynClient, &crdOverlay, resources, resourceMapper.MappingForKind)
+    if err != nil {
+      return fmt.Errorf("failed to diff overlay %q: %w", crdOverlay.Name, err)
+    }
+
+    diff.Print(out, append(results, pruned...))
+  }
+  return nil
+}
+
 func main() {
 
 	// Create the root command for Cobra
@@ -97,6 +305,32 @@ func main() {
       metricsServerPort, _ := cmd.Flags().GetString("metricsServerPort")
       if controllerName == "" { controllerName  = viper.GetString("METRICS_SERVER_PORT") }
 
+      leaderElection, _ := cmd.Flags().GetBool("leaderElection")
+      if !cmd.Flags().Changed("leaderElection") { leaderElection = viper.GetBool("LEADER_ELECTION") }
+
+      leaseName, _ := cmd.Flags().GetString("leaderElectionLeaseName")
+      if leaseName == "" { leaseName = viper.GetString("LEADER_ELECTION_LEASE_NAME") }
+
+      leaseNamespace, _ := cmd.Flags().GetString("leaderElectionNamespace")
+      if leaseNamespace == "" { leaseNamespace = viper.GetString("LEADER_ELECTION_NAMESPACE") }
+
+      leaseDuration, _ := cmd.Flags().GetDuration("leaderElectionLeaseDuration")
+      if !cmd.Flags().Changed("leaderElectionLeaseDuration") && viper.IsSet("LEADER_ELECTION_LEASE_DURATION") { leaseDuration = viper.GetDuration("LEADER_ELECTION_LEASE_DURATION") }
+
+      renewDeadline, _ := cmd.Flags().GetDuration("leaderElectionRenewDeadline")
+      if !cmd.Flags().Changed("leaderElectionRenewDeadline") && viper.IsSet("LEADER_ELECTION_RENEW_DEADLINE") { renewDeadline = viper.GetDuration("LEADER_ELECTION_RENEW_DEADLINE") }
+
+      retryPeriod, _ := cmd.Flags().GetDuration("leaderElectionRetryPeriod")
+      if !cmd.Flags().Changed("leaderElectionRetryPeriod") && viper.IsSet("LEADER_ELECTION_RETRY_PERIOD") { retryPeriod = viper.GetDuration("LEADER_ELECTION_RETRY_PERIOD") }
+
+      requestTimeout, _ := cmd.Flags().GetDuration("requestTimeout")
+      if !cmd.Flags().Changed("requestTimeout") && viper.IsSet("REQUEST_TIMEOUT") { requestTimeout = viper.GetDuration("REQUEST_TIMEOUT") }
+
+      reconcileTimeout, _ := cmd.Flags().GetDuration("reconcileTimeout")
+      if !cmd.Flags().Changed("reconcileTimeout") && viper.IsSet("RECONCILE_TIMEOUT") { reconcileTimeout = viper.GetDuration("RECONCILE_TIMEOUT") }
+
+      sourceEnvAllowList, overlayEnvAllowList := environmentFlags(cmd)
+
 			// Initialize klog
 			klog.InitFlags(nil)
 
@@ -117,7 +351,17 @@ func main() {
 				SetNamespaceFilter(namespaceFilter).
 				SetSourceConfiguration(sourceConfiguration).
         SetUpdateReadyz(setReadyz).
-        SetUpdateHealthz(setHealhtz)
+        SetUpdateHealthz(setHealhtz).
+        SetLeaderElection(leaderElection).
+        SetLeaseName(leaseName).
+        SetLeaseNamespace(leaseNamespace).
+        SetLeaseDuration(leaseDuration).
+        SetRenewDeadline(renewDeadline).
+        SetRetryPeriod(retryPeriod).
+        SetRequestTimeout(requestTimeout).
+        SetReconcileTimeout(reconcileTimeout).
+        SetSourceEnvAllowList(sourceEnvAllowList).
+        SetOverlayEnvAllowList(overlayEnvAllowList)
 
 			// Construct the controller
 			controllerClient, err := 
@@ -171,12 +415,192 @@ func main() {
     "8080",
     "Healthz server port (defaults to '8080')",
   )
+  runCmd.Flags().Bool(
+    "leaderElection",
+    false,
+    "Run the workers only while holding the leader election Lease (defaults to false)",
+  )
+  runCmd.Flags().String(
+    "leaderElectionLeaseName",
+    "",
+    "Name of the leader election Lease (defaults to the controller name)",
+  )
+  runCmd.Flags().String(
+    "leaderElectionNamespace",
+    "",
+    "Namespace of the leader election Lease (defaults to 'default')",
+  )
+  runCmd.Flags().Duration(
+    "leaderElectionLeaseDuration",
+    15*time.Second,
+    "Duration followers wait before taking over a not renewed Lease (defaults to 15s)",
+  )
+  runCmd.Flags().Duration(
+    "leaderElectionRenewDeadline",
+    10*time.Second,
+    "Duration the leader retries renewing the Lease before giving up (defaults to 10s)",
+  )
+  runCmd.Flags().Duration(
+    "leaderElectionRetryPeriod",
+    2*time.Second,
+    "Duration between two leader election attempts (defaults to 2s)",
+  )
+  runCmd.Flags().Duration(
+    "requestTimeout",
+    30*time.Second,
+    "Timeout of a single Kubernetes API request (defaults to 30s)",
+  )
+  runCmd.Flags().Duration(
+    "reconcileTimeout",
+    5*time.Minute,
+    "Timeout of the reconcile of a single overlay (defaults to 5m)",
+  )
+  addEnvironmentFlags(runCmd)
+
+  // Create the render command, runs the merge pipeline without a cluster
+  var renderCmd = &cobra.Command{
+    Use:   "render",
+    Short: "Render overlays offline and print the merged manifests",
+    RunE: func(cmd *cobra.Command, args []string) error {
+      sourcePath, _ := cmd.Flags().GetString("source")
+      overlayPath, _ := cmd.Flags().GetString("overlay")
+      namespace, _ := cmd.Flags().GetString("namespace")
+      return renderOverlays(cmd.Context(), cmd.OutOrStdout(), sourcePath, overlayPath, namespace, environment(cmd))
+    },
+  }
+
+  renderCmd.Flags().String(
+    "source",
+    "/opt/kubeforge/sourceConfiguration.yaml",
+    "Path to the source configuration file (defaults to '/opt/kubeforge/sourceConfiguration.yaml')",
+  )
+  renderCmd.Flags().String(
+    "overlay",
+    "",
+    "Path to the file holding the overlay(s) to render",
+  )
+  renderCmd.Flags().String(
+    "namespace",
+    "default",
+    "Namespace of overlays without one (defaults to 'default')",
+  )
+  addEnvironmentFlags(renderCmd)
+  renderCmd.MarkFlagRequired("overlay")
+
+  // Create the diff command, compares an overlay with the live cluster
+  var diffCmd = &cobra.Command{
+    Use:   "diff",
+    Short: "Diff rendered overlays against the live cluster",
+    RunE: func(cmd *cobra.Command, args []string) error {
+      kubernetesConfig, _ := cmd.Flags().GetString("kubernetesConfig")
+      if kubernetesConfig == "" { kubernetesConfig = viper.GetString("KUBERNETES_CONFIG") }
+
+      kubernetesAddress, _ := cmd.Flags().GetString("kubernetesAddress")
+      if kubernetesAddress == "" { kubernetesAddress = viper.GetString("KUBERNETES_ADDRESS") }
+
+      controllerName, _ := cmd.Flags().GetString("controllerName")
+      sourcePath, _ := cmd.Flags().GetString("source")
+      overlayPath, _ := cmd.Flags().GetString("overlay")
+      overlayName, _ := cmd.Flags().GetString("name")
+      namespace, _ := cmd.Flags().GetString("namespace")
+
+      return diffOverlays(
+        cmd.Context(),
+        cmd.OutOrStdout(),
+        kubernetesConfig,
+        kubernetesAddress,
+        controllerName,
+        sourcePath,
+        overlayPath,
+        overlayName,
+        namespace,
+        environment(cmd),
+      )
+    },
+  }
+
+  diffCmd.Flags().String(
+    "kubernetesConfig",
+    "",
+    "Path to the Kubernetes configuration file (optional)",
+  )
+  diffCmd.Flags().String(
+    "kubernetesAddress",
+    "",
+    "Address of the Kubernetes API server (optional)",
+  )
+  diffCmd.Flags().String(
+    "controllerName",
+    "kubeforge",
+    "Name of the controller, used as field manager (defaults to 'kubeforge')",
+  )
+  diffCmd.Flags().String(
+    "source",
+    "/opt/kubeforge/sourceConfiguration.yaml",
+    "Path to the source configuration file (defaults to '/opt/kubeforge/sourceConfiguration.yaml')",
+  )
+  diffCmd.Flags().String(
+    "overlay",
+    "",
+    "Path to the file holding the overlay(s) to diff",
+  )
+  diffCmd.Flags().String(
+    "name",
+    "",
+    "Name of the overlay to diff, read from the cluster",
+  )
+  diffCmd.Flags().String(
+    "namespace",
+    "default",
+    "Namespace of the overlay(s) (defaults to 'default')",
+  )
+  addEnvironmentFlags(diffCmd)
 
   var rootCmd = &cobra.Command{Use: "kubeforge"}
   rootCmd.AddCommand(runCmd)
+  rootCmd.AddCommand(renderCmd)
+  rootCmd.AddCommand(diffCmd)
   rootCmd.Execute()
 }
 
+// addEnvironmentFlags adds the flags of the variable substitution.
+func addEnvironmentFlags(cmd *cobra.Command) {
+  cmd.Flags().String(
+    "sourceEnvAllowList",
+    "*",
+    "Variables substituted in the source configuration file, comma separated names or prefixes ending with '*' (defaults to '*')",
+  )
+  cmd.Flags().String(
+    "overlayEnvAllowList",
+    "",
+    "Variables substituted in the overlay data and OverlaySources, comma separated names or prefixes ending with '*' (defaults to none)",
+  )
+}
+
+// environmentFlags returns the allow-lists of the variable substitution,
+// the environment is used for flags not set.
+func environmentFlags(cmd *cobra.Command) (string, string) {
+  sourceEnvAllowList, _ := cmd.Flags().GetString("sourceEnvAllowList")
+  if !cmd.Flags().Changed("sourceEnvAllowList") && viper.IsSet("SOURCE_ENV_ALLOW_LIST") {
+    sourceEnvAllowList = viper.GetString("SOURCE_ENV_ALLOW_LIST")
+  }
+  overlayEnvAllowList, _ := cmd.Flags().GetString("overlayEnvAllowList")
+  if !cmd.Flags().Changed("overlayEnvAllowList") && viper.IsSet("OVERLAY_ENV_ALLOW_LIST") {
+    overlayEnvAllowList = viper.GetString("OVERLAY_ENV_ALLOW_LIST")
+  }
+  return sourceEnvAllowList, overlayEnvAllowList
+}
+
+// environment returns the variable substitution of the render and diff
+// commands.
+func environment(cmd *cobra.Command) render.Environment {
+  sourceEnvAllowList, overlayEnvAllowList := environmentFlags(cmd)
+  return render.Environment{
+    Source:  kubeforgeYaml.NewExpander(sourceEnvAllowList),
+    Overlay: kubeforgeYaml.NewExpander(overlayEnvAllowList),
+  }
+}
+
 // initConfig reads in the environment variables
 func initConfig() {
 	viper.AutomaticEnv()
@@ -39,6 +39,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Overlay{},
 		&OverlayList{},
+		&OverlaySource{},
+		&OverlaySourceList{},
 	)
 	v1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
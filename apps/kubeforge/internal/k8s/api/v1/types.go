@@ -29,13 +29,163 @@ type Overlay struct {
 // OverlaySpec is the spec for a Overlay resource
 type OverlaySpec struct {
   Data runtime.RawExtension `json:"data,omitempty"`
+
+  // Prune deletes resources that are no longer rendered by the overlay,
+  // defaults to true when unset
+  Prune *bool `json:"prune,omitempty"`
+
+  // SourceRef selects the OverlaySource to render on top of, the source
+  // configuration file of the controller is used when unset
+  SourceRef *OverlaySourceReference `json:"sourceRef,omitempty"`
+
+  // DriftPolicy tells what to do with resources changed outside of the
+  // overlay, one of enforce (default), warn or ignore
+  DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+  // Patches are applied in order to the rendered resources, after the
+  // source configuration and the data are merged
+  Patches []OverlayPatch `json:"patches,omitempty"`
+
+  // Values are available to the templates of the data and the source
+  // configuration as `.Values`
+  Values *runtime.RawExtension `json:"values,omitempty"`
+
+  // Templates turns the evaluation of the templates of the merged data on,
+  // entries may override it with their `templates` key
+  Templates bool `json:"templates,omitempty"`
+
+  // Bases are Overlays of the namespace whose data is merged in order
+  // between the source configuration and the data of the overlay
+  Bases []OverlayBaseReference `json:"bases,omitempty"`
+
+  // Abstract marks an overlay only meant to be a base, it is neither
+  // rendered nor applied and the resources it applied before are pruned
+  Abstract bool `json:"abstract,omitempty"`
+}
+
+// OverlayPatch patches the rendered resources matching its target, with
+// either a JSON patch or a strategic merge fragment
+type OverlayPatch struct {
+  Target OverlayPatchTarget `json:"target"`
+
+  // JSONPatch is a list of RFC 6902 operations
+  JSONPatch []JSONPatchOperation `json:"jsonPatch,omitempty"`
+
+  // StrategicMerge is a fragment merged into the resources the way the data
+  // is, merge directives included
+  StrategicMerge *runtime.RawExtension `json:"strategicMerge,omitempty"`
+}
+
+// OverlayPatchTarget selects rendered resources, every field set has to
+// match
+type OverlayPatchTarget struct {
+  Group   string `json:"group,omitempty"`
+  Version string `json:"version,omitempty"`
+  Kind    string `json:"kind,omitempty"`
+
+  // Name is the final name of the resource
+  Name    string `json:"name,omitempty"`
+
+  // LabelSelector is a label selector (e.g. "app=web,tier!=cache")
+  LabelSelector string `json:"labelSelector,omitempty"`
+}
+
+// JSONPatchOperation is a single RFC 6902 operation
+type JSONPatchOperation struct {
+  Op    string                `json:"op"`
+  Path  string                `json:"path"`
+  From  string                `json:"from,omitempty"`
+
+  // Value is kept when null, add, replace and test take null as a value
+  Value *runtime.RawExtension `json:"value"`
+}
+
+// DriftPolicy is the handling of resources changed outside of the overlay
+type DriftPolicy string
+
+const (
+  // DriftPolicyEnforce reverts the drifted fields
+  DriftPolicyEnforce DriftPolicy = "enforce"
+
+  // DriftPolicyWarn reports drifted resources through events and the
+  // Drifted condition
+  DriftPolicyWarn    DriftPolicy = "warn"
+
+  // DriftPolicyIgnore does not look for drift
+  DriftPolicyIgnore  DriftPolicy = "ignore"
+)
+
+// OverlaySourceReference references a OverlaySource in the namespace of the
+// Overlay
+type OverlaySourceReference struct {
+  Name string `json:"name"`
+}
+
+// OverlayBaseReference references a base Overlay in the namespace of the
+// Overlay
+type OverlayBaseReference struct {
+  Name string `json:"name"`
 }
 
 // OverlayStatus is the status for a Overlay resource
 type OverlayStatus struct {
   Data runtime.RawExtension `json:"data,omitempty"`
+
+  // Conditions are the latest observations of the overlay state
+  Conditions []v1.Condition `json:"conditions,omitempty"`
+
+  // ObservedGeneration is the generation last processed by the controller
+  ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+  // LastAppliedHash is the hash of the last fully applied render
+  LastAppliedHash string `json:"lastAppliedHash,omitempty"`
+
+  // SourceConfigurationHash is the hash of the source configuration used
+  SourceConfigurationHash string `json:"sourceConfigurationHash,omitempty"`
+
+  // Resources is the inventory of resources applied by the overlay
+  Resources []OverlayResource `json:"resources,omitempty"`
+}
+
+// OverlayResource identifies a single resource applied by a Overlay
+// together with the outcome of its last apply
+type OverlayResource struct {
+  Group     string `json:"group,omitempty"`
+  Version   string `json:"version"`
+  Kind      string `json:"kind"`
+  Namespace string `json:"namespace,omitempty"`
+  Name      string `json:"name"`
+
+  // Action is the last action taken on the resource (e.g. Created)
+  Action    string `json:"action,omitempty"`
+
+  // Error is the error returned by the last apply, if any
+  Error     string `json:"error,omitempty"`
+
+  // Health is the kstatus status of the live resource (Current,
+  // InProgress or Failed)
+  Health    string `json:"health,omitempty"`
+
+  // Message explains the health, or what the resource is waiting for
+  Message   string `json:"message,omitempty"`
 }
 
+// Condition types of a Overlay resource
+const (
+  // OverlayConditionReady means all resources of the overlay were applied
+  OverlayConditionReady       = "Ready"
+
+  // OverlayConditionReconciling means the controller is still converging
+  OverlayConditionReconciling = "Reconciling"
+
+  // OverlayConditionStalled means the controller can not make progress
+  OverlayConditionStalled     = "Stalled"
+
+  // OverlayConditionDrifted means resources were changed outside of the
+  // overlay and are left as they are
+  OverlayConditionDrifted     = "Drifted"
+)
+
 // ------------------------------------------------------------
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -46,3 +196,35 @@ type OverlayList struct {
 
 	Items []Overlay `json:"items"`
 }
+
+// ------------------------------------------------------------
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OverlaySource is a specification for a OverlaySource resource, it holds a
+// named base configuration Overlays are rendered on top of
+type OverlaySource struct {
+	runtime.TypeMeta `json:",inline"`
+	v1.ObjectMeta    `json:"metadata,omitempty"`
+
+	Spec OverlaySourceSpec `json:"spec"`
+}
+
+// OverlaySourceSpec is the spec for a OverlaySource resource
+type OverlaySourceSpec struct {
+  // Data holds the base configuration, same format as the source
+  // configuration file
+  Data runtime.RawExtension `json:"data,omitempty"`
+}
+
+// ------------------------------------------------------------
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OverlaySourceList is a list of OverlaySource resources
+type OverlaySourceList struct {
+	v1.TypeMeta `json:",inline"`
+	v1.ListMeta `json:"metadata"`
+
+	Items []OverlaySource `json:"items"`
+}
@@ -28,19 +28,19 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	stdErrors "errors"
 	"fmt"
-	"regexp"
 	"strings"
+	"sync"
 	"time"
 
-	"dario.cat/mergo"
-
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -50,11 +50,10 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	pkgRuntime "k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	crdv1 "kubeforge/internal/k8s/api/v1"
-	yaml "kubeforge/internal/ops/yaml"
-	yamlMisc "kubeforge/internal/ops/yaml/misc"
+	"kubeforge/internal/k8s/render"
 
 	crdClientSet "kubeforge/pkg/generated/clientset/versioned"
 	crdListers "kubeforge/pkg/generated/listers/api/v1"
@@ -62,35 +61,90 @@ import (
 	controllerMisc "kubeforge/internal/k8s/controller/misc"
 )
 
+// resourceAction describes the outcome of applying a single resource.
+type resourceAction string
+
+const (
+  resourceActionUnchanged  resourceAction = "Unchanged"
+  resourceActionCreated    resourceAction = "Created"
+  resourceActionConfigured resourceAction = "Configured"
+  resourceActionRecreated  resourceAction = "Recreated"
+  resourceActionPruned     resourceAction = "Pruned"
+  resourceActionDrifted    resourceAction = "Drifted"
+  resourceActionReverted   resourceAction = "Reverted"
+  resourceActionWaiting    resourceAction = "Waiting"
+)
+
+// recreateRequeueDelay is the delay before an overlay with recreated
+// resources is processed again.
+const recreateRequeueDelay = 5 * time.Second
+
+// dependencyRequeueDelay is the delay before an overlay with resources
+// waiting for their dependencies is processed again.
+const dependencyRequeueDelay = 10 * time.Second
+
 type controller struct {
   workingContext            context.Context
 	workingWorkers	          int
 	workqueue                 workqueue.TypedRateLimitingInterface[cache.ObjectName]  
   recorder                  record.EventRecorder
   controllerName            string
-  dynInformers              []cache.SharedInformer
   k8sClient                 kubernetes.Interface
   crdClient                 crdClientSet.Interface
   dynClient                 dynamic.Interface 
+  namespaceFilter           string
+  watchMutex                sync.Mutex
+  resourceWatches           map[schema.GroupVersionResource]*resourceWatch
+  watchUsers                map[cache.ObjectName]map[schema.GroupVersionResource]bool
+  resourceMapper            *controllerMisc.ResourceMapper
+  mergeSchemas              *controllerMisc.MergeSchemas
   crdLister                 crdListers.OverlayLister
+  crdIndexer                cache.Indexer
   crdsSynced                cache.InformerSynced
+  crdSourceLister           crdListers.OverlaySourceLister
+  crdSourcesSynced          cache.InformerSynced
   sourceConfiguration       string
+  sourceMutex               sync.RWMutex
+  sourceContent             string
+  sourceHash                string
 	updateReadyz              func(bool)
 	updateHealthz             func(bool)
+  leaderElection            bool
+  leaseName                 string
+  leaseNamespace            string
+  leaseDuration             time.Duration
+  renewDeadline             time.Duration
+  retryPeriod               time.Duration
+  requestTimeout            time.Duration
+  reconcileTimeout          time.Duration
+  environment               render.Environment
 }
 
 // Run will set up the event handlers for types we are interested in, as well
 // as syncing informer caches and starting workers. It will block until stopCh
 // is closed, at which point it will shutdown the workqueue and wait for
-// workers to finish processing their current work items.
+// workers to finish processing their current work items. With leader
+// election enabled the workers only run while leading.
 func (controller *controller) Run() error {
 
   defer controller.updateReadyz(false)
   defer controller.updateHealthz(false)
 
+  // Healthy until a reconcile fails, ready once the workers run
+  controller.updateHealthz(true)
+
+  if controller.leaderElection {
+    return controller.runWithLeaderElection()
+  }
+  return controller.run(controller.workingContext)
+}
+
+// run syncs the informer caches and runs the workers until ctx is done.
+func (controller *controller) run(ctx context.Context) error {
+
 	defer runtime.HandleCrash()
 	defer controller.workqueue.ShutDown()
-	logger := klog.FromContext(controller.workingContext)
+	logger := klog.FromContext(ctx)
 
 	// Start the informer factories to begin populating the informer caches
 	logger.Info("Starting Controller")
@@ -98,42 +152,51 @@ func (controller *controller) Run() error {
 	// Wait for the caches to be synced before starting workers
 	logger.Info("Waiting for informer caches to sync")
 
-	// Create a slice to hold HasSynced functions for dynamic resources
-	var dynamicHasSynced []cache.InformerSynced
-
-	// Assuming you have dynamic informers set up for Pods, PVCs, ConfigMaps
-	for _, informer := range controller.dynInformers {
-		dynamicHasSynced = append(dynamicHasSynced, informer.HasSynced)
-	}
-  dynamicHasSynced = append(dynamicHasSynced, controller.crdsSynced)
-
-  // Wait for syncs
+  // Wait for syncs, child resource informers are started on demand
   ok := cache.WaitForCacheSync(
-    controller.workingContext.Done(), 
-    dynamicHasSynced...,
+    ctx.Done(), 
+    controller.crdsSynced,
+    controller.crdSourcesSynced,
   );
 	if !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
+  // Reload the source configuration whenever it changes
+  go func() {
+    if err := controller.watchSourceConfiguration(ctx); err != nil {
+      runtime.HandleErrorWithContext(ctx, err, "Source configuration is not watched")
+    }
+  }()
+
 	logger.Info("Starting workers", "count", controller.workingWorkers)
 
   // Set ready just before launching 
   controller.updateReadyz(true)
 
 	// Launch two workers to process resources
+	var workers sync.WaitGroup
 	for i := 0; i < controller.workingWorkers; i++ {
-		go wait.UntilWithContext(
-      controller.workingContext, 
-      controller.runWorker, 
-      time.Second,
-    )
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			wait.UntilWithContext(
+        ctx, 
+        controller.runWorker, 
+        time.Second,
+      )
+		}()
 	}
 
 	logger.Info("Started workers")
-	<-controller.workingContext.Done()
+	<-ctx.Done()
 	logger.Info("Shutting down workers")
 
+	// The requests in flight are cancelled, wait for the workers to return
+	controller.workqueue.ShutDown()
+	workers.Wait()
+	logger.Info("Workers stopped")
+
 	return nil
 }
 
@@ -180,57 +243,238 @@ func (controller *controller) processNextWorkItem(ctx context.Context) bool {
 }
 
 // syncHandler compares the actual state with the desired, and attempts to converge the two. 
-func (controller *controller) syncHandler (ctx context.Context, obj cache.ObjectName) error {
+func (controller *controller) syncHandler (workerCtx context.Context, obj cache.ObjectName) error {
+    // The reconcile is bounded by its timeout, the status is written with
+    // the worker context so a timed out reconcile is still recorded
+    ctx, cancel := controller.reconcileContext(workerCtx)
+    defer cancel()
+
 	  logger := klog.FromContext(ctx)
 
     // Get the overllay ~ CRD
     crdOverlay, err := controller.getCRDOverlay(obj, logger)
+    if errors.IsNotFound(err) {
+        // The overlay was deleted, its resources are no longer watched
+        controller.releaseResourceWatches(obj, logger)
+        driftedResources.DeleteLabelValues(obj.Namespace, obj.Name)
+        return nil
+    }
     if err != nil {
         return err
     }    
 
-    // Unmarshal custom YAML data
-    customData, err := controller.unmarshalCustomYAML(crdOverlay, logger)
+    // Clean up the cluster-scoped resources of a deleted overlay
+    if crdOverlay.DeletionTimestamp != nil {
+        return controller.finalizeOverlay(ctx, crdOverlay, logger)
+    }
+
+    // Reconcile the overlay and record the outcome in its status, the copy
+    // picks up the finalizer when one is added
+    crdOverlay = crdOverlay.DeepCopy()
+    status := crdOverlay.Status.DeepCopy()
+    result, syncErr := controller.syncOverlay(ctx, obj, crdOverlay, status, logger)
+    setOverlayConditions(status, crdOverlay.Generation, result, syncErr)
+    if syncErr == nil {
+        setDriftCondition(status, crdOverlay, result)
+    }
+
+    if err := controller.updateStatus(workerCtx, crdOverlay, status); err != nil {
+        logger.Error(err, "Failed to update overlay status")
+        if syncErr == nil {
+            return err
+        }
+    }
+    if syncErr != nil {
+        return syncErr
+    }
+
+    // Requeue the overlay with backoff while some of its resources fail
+    if len(result.applyErrors) > 0 {
+        return fmt.Errorf("%d resource(s) failed to apply: %w", len(result.applyErrors), utilerrors.NewAggregate(result.applyErrors))
+    }
+
+  controller.recorder.Event(crdOverlay, corev1.EventTypeNormal, "Success", "Success")
+  return nil
+}
+
+// syncOverlay renders the overlay, applies its resources and prunes the ones
+// no longer rendered. The inventory and last applied hash are written to the
+// given status.
+func (controller *controller) syncOverlay(
+  ctx        context.Context,
+  obj        cache.ObjectName,
+  crdOverlay *crdv1.Overlay,
+  status     *crdv1.OverlayStatus,
+  logger     klog.Logger,
+) (
+  syncResult,
+  error,
+) {
+
+    result := syncResult{}
+
+    // Abstract overlays are only merged into others, their resources are
+    // no longer watched
+    if crdOverlay.Spec.Abstract {
+        controller.releaseResourceWatches(obj, logger)
+        driftedResources.DeleteLabelValues(obj.Namespace, obj.Name)
+        return controller.syncAbstractOverlay(ctx, crdOverlay, status, logger)
+    }
+
+    // Unmarshal custom YAML data of the bases and of the overlay
+    customLayers, err := controller.unmarshalCustomYAML(crdOverlay, logger)
     if err != nil {
-        return err
+        return result, err
     }
 
     // Unmarshal default YAML configuration
-    sourceData, err := controller.unmarshalSourceYAML(logger)
+    sourceConfiguration, sourceHash, err := controller.getOverlaySourceConfiguration(crdOverlay)
     if err != nil {
-        return err
+        return result, err
+    }
+    status.SourceConfigurationHash = sourceHash
+    sourceData, err := controller.unmarshalSourceYAML(crdOverlay, sourceConfiguration, logger)
+    if err != nil {
+        return result, err
     }        
 
     // Merge YAML data
-    dataMergedMap, err := controller.mergeYAML(sourceData, customData)
+    dataMergedMap, err := controller.mergeYAML(sourceData, customLayers)
     if err != nil {
-        return err
+        return result, err
+    }
+
+    // Evaluate the templates of the merged data
+    if err := render.Template(ctx, dataMergedMap, crdOverlay); err != nil {
+        return result, err
     }
 
     // Set up default metadata
     objectMetadata, err := controller.getMetadata(crdOverlay, logger)
     if err != nil {
-        return err
+        return result, err
     }       
+
+    // Render the resources
+    resources, err := render.Resources(
+      dataMergedMap, 
+      objectMetadata, 
+      func(resourceType string) (*meta.RESTMapping, error) {
+        return controller.getResourceSchema(resourceType, logger)
+      },
+    )
+    if err != nil {
+        return result, err
+    }
+
+    // Patch the rendered resources
+    if err := render.Patch(resources, crdOverlay.Spec.Patches, controller.mergeSchemas.For); err != nil {
+        return result, err
+    }
+
+    // Cluster-scoped resources are deleted by the controller, not the
+    // garbage collector
+    if hasClusterScopedResources(resources) {
+        if err := controller.ensureFinalizer(ctx, crdOverlay, logger); err != nil {
+            return result, err
+        }
+    }
+
+    // Watch the rendered resources
+    controller.updateResourceWatches(obj, resources, logger)
+
+    // Order the resources by kind and declared dependencies
+    steps, err := render.Order(resources)
+    if err != nil {
+        return result, err
+    }
     
-    // Iterate over resource types
-    discoveryClient := controller.k8sClient.Discovery()
-    for resourceType, resourceList := range dataMergedMap {
+    // Apply the resources, a resource waits until its dependencies are ready
+    renderedResources := []crdv1.OverlayResource{}
+    driftPolicy := overlayDriftPolicy(crdOverlay)
+    readiness := make([]bool, len(steps))
+    for index, step := range steps {
+      resource := step.Resource
+      kind := resource.Object.GroupVersionKind()
+
+      if waitingFor := waitingDependency(steps, step, readiness); waitingFor != "" {
+          logger.V(2).Info("Waiting for dependency", "resource", klog.KObj(resource.Object), "kind", kind.Kind, "dependency", waitingFor)
+          renderedResources = append(renderedResources, waitingResourceResult(kind, resource.Object.GetNamespace(), resource.Object.GetName(), waitingFor))
+          result.waiting++
+          continue
+      }
 
-      schema, err := controller.getResourceSchema(resourceType, discoveryClient, logger)
+      previousAction := previousResourceAction(crdOverlay, kind, resource.Object.GetNamespace(), resource.Object.GetName())
+      action, liveResource, err := controller.processResource(ctx, crdOverlay, resource, driftPolicy, logger)
+      renderedResource := overlayResourceResult(crdOverlay, kind, resource.Object.GetNamespace(), resource.Object.GetName(), action, err)
       if err != nil {
-          return err
+          // Keep applying the other resources, the errors are returned once
+          // the status is recorded
+          renderedResources = append(renderedResources, renderedResource)
+          result.failed++
+          var conflict *ownershipConflictError
+          if stdErrors.As(err, &conflict) {
+              result.conflicts++
+          }
+          result.applyErrors = append(result.applyErrors, fmt.Errorf("%s/%s %q: %w", kind.GroupVersion().String(), kind.Kind, resource.Object.GetName(), err))
+          controller.recordResourceError(crdOverlay, kind, resource.Object.GetName(), err)
+          continue
+      }
+
+      // Roll the health of the live resource up into the overlay
+      health, message := controllerMisc.ComputeHealth(liveResource)
+      renderedResource.Health, renderedResource.Message = string(health), message
+      renderedResources = append(renderedResources, renderedResource)
+      readiness[index] = health == controllerMisc.HealthCurrent
+      switch health {
+      case controllerMisc.HealthInProgress:
+          result.progressing++
+      case controllerMisc.HealthFailed:
+          result.unhealthy++
+      }
+
+      // Drift left in place is only reported once
+      if action == resourceActionDrifted {
+          result.drifted++
+          if previousAction == string(resourceActionDrifted) {
+              continue
+          }
+      }
+      if action == resourceActionDrifted || action == resourceActionReverted {
+          driftDetections.WithLabelValues(string(driftPolicy)).Inc()
       }
 
-      for _, resourceDefinition := range resourceList.([]interface{}) {
-        if err := controller.processResource(resourceDefinition, schema, objectMetadata, logger); err != nil {
-            continue
-        }        
+      controller.recordResourceAction(crdOverlay, kind, resource.Object.GetName(), action)
+      if action == resourceActionRecreated {
+          result.pending = true
       }
     }
+    driftedResources.WithLabelValues(crdOverlay.Namespace, crdOverlay.Name).Set(float64(result.drifted))
 
-  controller.recorder.Event(crdOverlay, corev1.EventTypeNormal, "Success", "Success")
-  return nil
+    // Prune resources which are no longer rendered, skipped when anything
+    // failed so a partial render never deletes live resources
+    status.Resources, err = controller.pruneResources(ctx, crdOverlay, renderedResources, result.failed == 0, logger)
+    if err != nil {
+        return result, err
+    }
+
+    if result.failed == 0 && result.waiting == 0 {
+        status.LastAppliedHash = renderHash(resources)
+    }
+
+    // Recreated resources are only deleted at this point, requeue the overlay
+    // so the next reconcile creates them once the deletion went through.
+    if result.pending {
+        controller.workqueue.AddAfter(obj, recreateRequeueDelay)
+    }
+
+    // Resources held back by their dependencies are applied by a later
+    // reconcile, the worker is not blocked meanwhile.
+    if result.waiting > 0 {
+        controller.workqueue.AddAfter(obj, dependencyRequeueDelay)
+    }
+
+    return result, nil
 }
 
 // ------------------------------------------------------------
@@ -245,10 +489,10 @@ func (controller *controller) getCRDOverlay(obj cache.ObjectName, logger klog.Lo
     return crdOverlay, nil
 }
 
-// unmarshalCustomYAML unmarshals the custom YAML data from the CRD overlay.
-func (controller *controller) unmarshalCustomYAML(crdOverlay *crdv1.Overlay, logger klog.Logger) (map[string]interface{}, error) {
-    var dataCustom map[string]interface{}
-    err := yaml.Unmarshal(string(crdOverlay.Spec.Data.Raw), &dataCustom, true)
+// unmarshalCustomYAML unmarshals the custom YAML data from the CRD overlay,
+// preceded by the data of its base overlays.
+func (controller *controller) unmarshalCustomYAML(crdOverlay *crdv1.Overlay, logger klog.Logger) ([]map[string]interface{}, error) {
+    dataCustom, err := render.UnmarshalLayers(crdOverlay, controller.getBaseOverlay, controller.environment)
     if err != nil {
         logger.Error(err, "Failed to unmarshal custom YAML")
         return nil, err
@@ -257,9 +501,8 @@ func (controller *controller) unmarshalCustomYAML(crdOverlay *crdv1.Overlay, log
 }
 
 // unmarshalDefaultYAML unmarshals the default YAML configuration.
-func (controller *controller) unmarshalSourceYAML(logger klog.Logger) (map[string]interface{}, error) {
-    var defaultRaw map[string]interface{}
-    err := yaml.Unmarshal(controller.sourceConfiguration, &defaultRaw, true)
+func (controller *controller) unmarshalSourceYAML(crdOverlay *crdv1.Overlay, sourceConfiguration string, logger klog.Logger) (map[string]interface{}, error) {
+    defaultRaw, err := render.UnmarshalSource(sourceConfiguration, controller.environment.SourceExpander(crdOverlay))
     if err != nil {
         logger.Error(err, "Error unmarshaling default YAML")
         return nil, err
@@ -267,17 +510,9 @@ func (controller *controller) unmarshalSourceYAML(logger klog.Logger) (map[strin
     return defaultRaw, nil
 }
 
-// mergeYAML merges the custom YAML with the default YAML configuration.
-func (controller *controller) mergeYAML(defaultRaw, dataCustom map[string]interface{}) (map[string]interface{}, error) {
-    dataMerged := yamlMisc.StructuresMergeByName(defaultRaw, dataCustom)
-    dataMergedMap := dataMerged.(map[string]interface{})
-    
-    err := mergo.Merge(&dataCustom, dataMergedMap, mergo.WithOverride)
-    if err != nil {
-        return nil, fmt.Errorf("error merging YAML: %v", err)
-    }
-
-    return dataMergedMap, nil
+// mergeYAML merges the layers of custom YAML with the default YAML configuration.
+func (controller *controller) mergeYAML(defaultRaw map[string]interface{}, dataCustom []map[string]interface{}) (map[string]interface{}, error) {
+    return render.Merge(defaultRaw, dataCustom, controller.resourceMapper.MappingFor, controller.mergeSchemas.For)
 }
 
 // getMetadata sets up the default Kubernetes object metadata.
@@ -288,144 +523,227 @@ func (controller *controller) getMetadata(
   metav1.ObjectMeta, 
   error,
 ) {
-
-    crdOverlayKind := crdOverlay.Kind
-    if crdOverlayKind == "" {
-	    re := regexp.MustCompile(`"kind":"([^"]+)"`)
-	    match := re.FindStringSubmatch(fmt.Sprint(crdOverlay))
-
-	    // Check if a match was found and print the result
-	    if len(match) > 1 {
-        crdOverlayKind = match[1]
-      } else {
-        logger.Error(nil, "Failed to get CRD overlay Kind")
-        return metav1.ObjectMeta{}, fmt.Errorf("Failed to get CRD overlay Kind")
-      }
-    }
-
-    objectMetadataReferences := []metav1.OwnerReference{
-        *metav1.NewControllerRef(crdOverlay, crdv1.SchemeGroupVersion.WithKind(crdOverlayKind)),
-    }
-    objectMetadata := metav1.ObjectMeta{
-        Namespace:       crdOverlay.Namespace,
-        OwnerReferences: objectMetadataReferences,
+    objectMetadata, err := render.Metadata(crdOverlay)
+    if err != nil {
+        logger.Error(err, "Failed to get CRD overlay Kind")
+        return metav1.ObjectMeta{}, err
     }
-
     return objectMetadata, nil
 }
 
-// getResourceSchema retrieves the schema of a given resource type.
+// getResourceSchema retrieves the schema, kind and scope of a given resource
+// type.
 func (controller *controller) getResourceSchema(
-  resourceType    string, 
-  discoveryClient discovery.DiscoveryInterface, 
-  logger          klog.Logger,
+  resourceType string, 
+  logger       klog.Logger,
 ) (
-  *schema.GroupVersionResource,
+  *meta.RESTMapping,
   error,
 ) {
-    schema, err := controllerMisc.GetGroupVersionResource(resourceType, discoveryClient)
+    mapping, err := controller.resourceMapper.MappingFor(resourceType)
     if err != nil {
         logger.Error(err, fmt.Sprintf("Error retrieving schema for resource type '%v'", resourceType))
         return nil, err
     }
-    return schema, nil
+    return mapping, nil
 }
 
-// processResource processes each resource (converts, compares, and applies changes).
+// processResource applies a single rendered resource and returns the action
+// taken on it along with the live object.
 func (controller *controller) processResource(
-  resourceDefinition interface{}, 
-  schema         *schema.GroupVersionResource, 
-  objectMetadata metav1.ObjectMeta, 
-  logger         klog.Logger,
-) error {
+  ctx         context.Context,
+  crdOverlay  *crdv1.Overlay,
+  resource    render.Resource, 
+  driftPolicy crdv1.DriftPolicy,
+  logger      klog.Logger,
+) (
+  resourceAction,
+  *unstructured.Unstructured,
+  error,
+) {
 
-    objMeta, err := pkgRuntime.DefaultUnstructuredConverter.ToUnstructured(&resourceDefinition)
-    if err != nil {
-        return fmt.Errorf("failed to convert resource to unstructured format: %v", err)
-    }
+    createdResource := resource.Object
+    resourceClient := controller.dynClient.Resource(resource.Schema).Namespace(createdResource.GetNamespace())
+    resourceName := createdResource.GetName()
 
-    marshaledData, _ := json.Marshal(objMeta)
-    appliedConfiguration := strings.ReplaceAll(string(marshaledData), "\n", " ")
-    metadataAnnotations := map[string]string{
-        "kubeforge.sh/last-applied-configuration": appliedConfiguration,
+    // Namespaced resources never leave the namespace of the overlay
+    if resource.Namespaced && createdResource.GetNamespace() != crdOverlay.Namespace {
+        return "", nil, fmt.Errorf("resource is rendered into namespace %q instead of %q", createdResource.GetNamespace(), crdOverlay.Namespace)
     }
 
-    createdResource := &unstructured.Unstructured{Object: objMeta}
-    createdResource.SetNamespace(objectMetadata.Namespace)
-
-    createdAnnotations := createdResource.GetAnnotations()
-    if createdAnnotations != nil {
-      for key, value := range metadataAnnotations {
-        createdAnnotations[key] = value
-      }
-      createdResource.SetAnnotations(createdAnnotations)
-    } else {
-      createdResource.SetAnnotations(metadataAnnotations)
+    // Cluster-scoped resources are only applied when owned by the overlay
+    var owner *crdv1.Overlay
+    if !resource.Namespaced {
+        owner = crdOverlay
     }
 
-    createdResource.SetOwnerReferences(objectMetadata.OwnerReferences)
-
-    overrideNameAnnotation := createdResource.GetAnnotations()["kubeforge.sh/override-name"]
-    if overrideNameAnnotation != "" {
-        createdResource.SetName(overrideNameAnnotation)
-    }
+    return controller.createOrUpdateResource(ctx, resourceClient, createdResource, resourceName, owner, driftPolicy, logger)
+}
 
-    resourceClient := controller.dynClient.Resource(*schema).Namespace(createdResource.GetNamespace())
-    resourceName := createdResource.GetName()
+// ownershipConflictError refuses to apply a cluster-scoped resource which
+// exists and is not owned by the overlay.
+type ownershipConflictError struct {
+  owner string
+}
 
-    return controller.createOrUpdateResource(resourceClient, createdResource, resourceName, logger)
+func (err *ownershipConflictError) Error() string {
+  if err.owner == "" {
+    return "resource already exists and is not owned by the overlay"
+  }
+  return fmt.Sprintf("resource already exists and is owned by overlay %q", err.owner)
 }
 
-// createOrUpdateResource checks if the resource exists and either updates or creates it.
+// createOrUpdateResource checks if the resource exists and either creates or
+// updates it through server-side apply. An up-to-date resource is checked
+// for drift and, depending on the drift policy, applied again or reported.
+// The resource is deleted (and created again by the next reconcile) only
+// when the API server refuses the apply because it touches an immutable
+// field, no live object is returned then. With an owner, an existing
+// resource not owned by it is refused.
 func (controller *controller) createOrUpdateResource(
+  ctx             context.Context,
   resourceClient  dynamic.ResourceInterface, 
   createdResource *unstructured.Unstructured, 
   resourceName    string, 
+  owner           *crdv1.Overlay,
+  driftPolicy     crdv1.DriftPolicy,
   logger          klog.Logger,
-) error {
+) (
+  resourceAction,
+  *unstructured.Unstructured,
+  error,
+) {
 
-    existingResource, err := resourceClient.Get(context.Background(), resourceName, metav1.GetOptions{})
+    logger = logger.WithValues("resource", klog.KObj(createdResource), "kind", createdResource.GetKind())
+
+    requestCtx, cancel := controller.requestContext(ctx)
+    existingResource, err := resourceClient.Get(requestCtx, resourceName, metav1.GetOptions{})
+    cancel()
     if err != nil && !errors.IsNotFound(err) {
-        return err
+        return "", nil, err
+    }
+    if errors.IsNotFound(err) {
+        existingResource = nil
     }
 
+    action := resourceActionCreated
     if existingResource != nil {
+        if owner != nil && !controllerMisc.IsOwnedBy(existingResource, owner) {
+            conflict := &ownershipConflictError{}
+            if ownerName, _, found := controllerMisc.GetOverlayOf(existingResource); found {
+                conflict.owner = ownerName.String()
+            }
+            logger.Info("Resource not owned by the overlay, not applying it", "owner", conflict.owner)
+            return "", nil, conflict
+        }
+        if existingResource.GetDeletionTimestamp() != nil {
+            return "", nil, fmt.Errorf("resource %q is being deleted, waiting before applying it again", resourceName)
+        }
+
         createdAnnotation := createdResource.GetAnnotations()["kubeforge.sh/last-applied-configuration"]
         existingAnnotation := existingResource.GetAnnotations()["kubeforge.sh/last-applied-configuration"]
 
+        action = resourceActionConfigured
         if createdAnnotation == existingAnnotation {
-            logger.Info("Resource already exists and is up-to-date")
-            return nil
+            if driftPolicy == crdv1.DriftPolicyIgnore {
+                logger.V(4).Info("Resource already exists and is up-to-date")
+                return resourceActionUnchanged, existingResource, nil
+            }
+
+            driftedFields, err := controllerMisc.DetectDrift(createdResource, existingResource, controller.controllerName, controller.mergeSchemas.ForKind(createdResource.GroupVersionKind()))
+            if err != nil {
+                return "", nil, err
+            }
+            if len(driftedFields) == 0 {
+                logger.V(4).Info("Resource already exists and is up-to-date")
+                return resourceActionUnchanged, existingResource, nil
+            }
+
+            logger.Info("Resource drifted", "fields", driftedFields, "policy", driftPolicy)
+            if driftPolicy == crdv1.DriftPolicyWarn {
+                return resourceActionDrifted, existingResource, nil
+            }
+            action = resourceActionReverted
         }
+    }
 
-        _, err = resourceClient.Create(
-          context.Background(), 
-          createdResource, 
-          metav1.CreateOptions{FieldManager: controller.controllerName, DryRun: []string{"All"}},
-        )
-        if err != nil {
-          logger.Error(err, "Validation failed")
-          return err
-        }
+    applyData, err := json.Marshal(createdResource.Object)
+    if err != nil {
+        return "", nil, fmt.Errorf("failed to marshal resource for apply: %v", err)
+    }
 
-        err := resourceClient.Delete(context.Background(), resourceName, metav1.DeleteOptions{})
-        if err != nil {
-            logger.Error(err, "Failed to delete existing resource")
-            return err
-        }
-    } else {
-        _, err := resourceClient.Create(
-          context.Background(), 
-          createdResource, 
-          metav1.CreateOptions{FieldManager: "controller"},
-        )
-        if err != nil {
-            logger.Error(err, "Failed to create resource")
-            return err
-        }
+    forceApply := true
+    requestCtx, cancel = controller.requestContext(ctx)
+    appliedResource, err := resourceClient.Patch(
+      requestCtx, 
+      resourceName, 
+      types.ApplyPatchType, 
+      applyData, 
+      metav1.PatchOptions{FieldManager: controller.controllerName, Force: &forceApply},
+    )
+    cancel()
+    if err == nil {
+        logger.Info("Resource applied", "action", action)
+        return action, appliedResource, nil
+    }
+
+    if existingResource == nil || !controllerMisc.IsImmutableFieldError(err) {
+        logger.Error(err, "Failed to apply resource")
+        return "", nil, err
+    }
+
+    // The change can not be applied in place, recreate the resource
+    logger.Info("Resource touches an immutable field, recreating it", "reason", err.Error())
+    propagationPolicy := metav1.DeletePropagationBackground
+    requestCtx, cancel = controller.requestContext(ctx)
+    err = resourceClient.Delete(
+      requestCtx, 
+      resourceName, 
+      metav1.DeleteOptions{PropagationPolicy: &propagationPolicy},
+    )
+    cancel()
+    if err != nil && !errors.IsNotFound(err) {
+        logger.Error(err, "Failed to delete existing resource")
+        return "", nil, err
     }
 
-    return nil
+    return resourceActionRecreated, nil, nil
+}
+
+// recordResourceAction emits an event on the overlay describing what happened
+// to one of its resources. Unchanged resources are not reported.
+func (controller *controller) recordResourceAction(
+  crdOverlay *crdv1.Overlay,
+  kind       schema.GroupVersionKind,
+  name       string,
+  action     resourceAction,
+) {
+    switch action {
+    case resourceActionCreated, resourceActionConfigured:
+        controller.recorder.Eventf(crdOverlay, corev1.EventTypeNormal, string(action), "%s %q %s", kind.Kind, name, strings.ToLower(string(action)))
+    case resourceActionRecreated:
+        controller.recorder.Eventf(crdOverlay, corev1.EventTypeWarning, string(action), "%s %q deleted to be recreated, an immutable field changed", kind.Kind, name)
+    case resourceActionDrifted:
+        controller.recorder.Eventf(crdOverlay, corev1.EventTypeWarning, string(action), "%s %q was changed outside of the overlay", kind.Kind, name)
+    case resourceActionReverted:
+        controller.recorder.Eventf(crdOverlay, corev1.EventTypeWarning, string(action), "%s %q was changed outside of the overlay, changes reverted", kind.Kind, name)
+    }
+}
+
+// recordResourceError emits a warning event on the overlay naming a resource
+// which failed to apply and the error returned by the API server.
+func (controller *controller) recordResourceError(
+  crdOverlay *crdv1.Overlay,
+  kind       schema.GroupVersionKind,
+  name       string,
+  err        error,
+) {
+    var conflict *ownershipConflictError
+    if stdErrors.As(err, &conflict) {
+        controller.recorder.Eventf(crdOverlay, corev1.EventTypeWarning, "Conflict", "%s/%s %q not applied: %v", kind.GroupVersion().String(), kind.Kind, name, err)
+        return
+    }
+    controller.recorder.Eventf(crdOverlay, corev1.EventTypeWarning, "ApplyFailed", "%s/%s %q failed to apply: %v", kind.GroupVersion().String(), kind.Kind, name, err)
 }
 
 // logSuccessEvent logs a success event after completing the operation.
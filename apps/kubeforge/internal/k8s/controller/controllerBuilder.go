@@ -15,12 +15,18 @@
 //    SetWorkingWorkers(5).
 //    SetDefaultDefinitionFilePath("/path/to/file").
 //    SetKubernetesAddress("https://k8s-cluster.com").
+//    SetLeaderElection(true).
+//    SetLeaseNamespace("kubeforge").
+//    SetRequestTimeout(30 * time.Second).
 //
 // ############################################################
 
 package controller
 
-import "context"
+import (
+  "context"
+  "time"
+)
 
 type controllerBuilder struct {
   kubernetesConfig    string          `mandatory:"false"`
@@ -32,6 +38,16 @@ type controllerBuilder struct {
   namespaceFilter     string          `mandatory:"false"`
 	updateReadyz        func(bool)      `mandatory:"true"`
 	updateHealthz       func(bool)      `mandatory:"true"`
+  leaderElection      bool            `mandatory:"false"`
+  leaseName           string          `mandatory:"false"`
+  leaseNamespace      string          `mandatory:"false"`
+  leaseDuration       time.Duration   `mandatory:"false"`
+  renewDeadline       time.Duration   `mandatory:"false"`
+  retryPeriod         time.Duration   `mandatory:"false"`
+  requestTimeout      time.Duration   `mandatory:"false"`
+  reconcileTimeout    time.Duration   `mandatory:"false"`
+  sourceEnvAllowList  string          `mandatory:"false"`
+  overlayEnvAllowList string          `mandatory:"false"`
 }
 func NewControllerBuilder() *controllerBuilder {
   return &controllerBuilder{}
@@ -72,3 +88,43 @@ func (controller *controllerBuilder) SetUpdateHealthz(updateHealthz func(bool))
 	controller.updateHealthz = updateHealthz 
 	return controller
 }
+func (controller *controllerBuilder) SetLeaderElection(leaderElection bool) *controllerBuilder {
+  controller.leaderElection = leaderElection
+  return controller
+}
+func (controller *controllerBuilder) SetLeaseName(leaseName string) *controllerBuilder {
+  controller.leaseName = leaseName
+  return controller
+}
+func (controller *controllerBuilder) SetLeaseNamespace(leaseNamespace string) *controllerBuilder {
+  controller.leaseNamespace = leaseNamespace
+  return controller
+}
+func (controller *controllerBuilder) SetLeaseDuration(leaseDuration time.Duration) *controllerBuilder {
+  controller.leaseDuration = leaseDuration
+  return controller
+}
+func (controller *controllerBuilder) SetRenewDeadline(renewDeadline time.Duration) *controllerBuilder {
+  controller.renewDeadline = renewDeadline
+  return controller
+}
+func (controller *controllerBuilder) SetRetryPeriod(retryPeriod time.Duration) *controllerBuilder {
+  controller.retryPeriod = retryPeriod
+  return controller
+}
+func (controller *controllerBuilder) SetRequestTimeout(requestTimeout time.Duration) *controllerBuilder {
+  controller.requestTimeout = requestTimeout
+  return controller
+}
+func (controller *controllerBuilder) SetReconcileTimeout(reconcileTimeout time.Duration) *controllerBuilder {
+  controller.reconcileTimeout = reconcileTimeout
+  return controller
+}
+func (controller *controllerBuilder) SetSourceEnvAllowList(allowList string) *controllerBuilder {
+  controller.sourceEnvAllowList = allowList
+  return controller
+}
+func (controller *controllerBuilder) SetOverlayEnvAllowList(allowList string) *controllerBuilder {
+  controller.overlayEnvAllowList = allowList
+  return controller
+}
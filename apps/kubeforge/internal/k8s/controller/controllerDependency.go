@@ -0,0 +1,36 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Resources are applied in the order of the apply plan (see
+// `render.Order`). A resource whose dependencies are not ready yet
+// is held back, the Overlay is requeued and the resource applied by
+// a later reconcile; events of the watched dependencies requeue it
+// as well.
+//
+// ############################################################
+
+package controller
+
+import (
+	"kubeforge/internal/k8s/render"
+)
+
+// waitingDependency returns the first dependency of the step not ready yet,
+// an empty string when the step can be applied.
+func waitingDependency(
+  steps     []render.Step,
+  step      render.Step,
+  readiness []bool,
+) string {
+
+    for _, dependency := range step.DependsOn {
+      if readiness[dependency] {
+        continue
+      }
+      object := steps[dependency].Resource.Object
+      return object.GetKind() + "/" + object.GetName()
+    }
+    return ""
+}
@@ -15,8 +15,8 @@
 // 2. **Kubernetes Clients**: Clients are initialized for interacting with Kubernetes API resources (e.g., K8S API server, 
 //    dynamic resources, and custom resources) through the `setupKubernetesClients` method.
 //
-// 3. **Dynamic Informers**: This component is responsible for setting up informers to watch standard Kubernetes resources 
-//    (e.g., pods, PVCs, config maps). The `setupDynamicInformer` method configures this part of the controller.
+// 3. **Dynamic Informers**: This component is responsible for setting up informers to watch the child resources
+//    rendered by the overlays, started on demand. The `setupDynamicInformer` method configures this part of the controller.
 //
 // 4. **Custom Resource Informers**: A custom informer for managing the custom resources, specifically for instances of 
 //    a custom CRD, is set up by `setupCustomResourceInformer`.
@@ -38,10 +38,8 @@ import (
 
 	"golang.org/x/time/rate"
 
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/kubernetes/typed/core/v1"
@@ -55,6 +53,10 @@ import (
 	crdScheme "kubeforge/pkg/generated/clientset/versioned/scheme"
 	crdInformeres "kubeforge/pkg/generated/informers/externalversions"
 
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
+	render "kubeforge/internal/k8s/render"
+	yaml "kubeforge/internal/ops/yaml"
+
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -85,14 +87,44 @@ func (director *controllerDirector) ConstructController() (*controller, error) {
     sourceConfiguration: director.builder.sourceConfiguration,
     updateHealthz:       director.builder.updateHealthz,
     updateReadyz:        director.builder.updateReadyz,   
+    requestTimeout:      director.builder.requestTimeout,
+    reconcileTimeout:    director.builder.reconcileTimeout,
+    environment:         render.Environment{
+      Source:  yaml.NewExpander(director.builder.sourceEnvAllowList),
+      Overlay: yaml.NewExpander(director.builder.overlayEnvAllowList),
+    },
 	}
 
+  // Unset timeouts fall back to the defaults
+  if controller.requestTimeout == 0 {
+    controller.requestTimeout = 30 * time.Second
+  }
+  if controller.reconcileTimeout == 0 {
+    controller.reconcileTimeout = 5 * time.Minute
+  }
+
+  // Setup leader election, unset values fall back to the defaults
+  director.setupLeaderElection(controller)
+
+  // Load the source configuration
+  logger.Info("Load source configuration")
+  if _, err := controller.loadSourceConfiguration(logger); err != nil {
+    return nil, err
+  }
+
   // Create Connection Configuration
   logger.Info("Create kubernetes connections")
   if err := director.setupKubernetesClients(controller); err != nil {
     return nil, err
   }
 
+  // Sets up the work queue, the informer handlers enqueue as soon as the
+  // informers are started
+  logger.Info("Create workqueue")
+  if err := director.setupWorkQueue(controller); err != nil {
+    return nil, err
+  }
+
   // Sets up dynamic informers for the specified resources
   logger.Info("Create kubernetes dynamic informer factory")
   if err := director.setupDynamicInformer(controller); err != nil {
@@ -105,12 +137,6 @@ func (director *controllerDirector) ConstructController() (*controller, error) {
     return nil, err
   }
 
-  // Sets up the work queue
-  logger.Info("Create workqueue")
-  if err := director.setupWorkQueue(controller); err != nil {
-    return nil, err
-  }
-
   // Sets up the event recorder
   logger.Info("Create event recorder")
   if err := director.setupEventRecorder(controller); err != nil {
@@ -158,6 +184,33 @@ func (director *controllerDirector) validateBuilder() error {
 	return nil
 }
 
+// setupLeaderElection copies the leader election configuration to the
+// controller, defaulting the Lease name to the controller name.
+func (director *controllerDirector) setupLeaderElection(controller *controller) {
+  controller.leaderElection = director.builder.leaderElection
+  controller.leaseName = director.builder.leaseName
+  controller.leaseNamespace = director.builder.leaseNamespace
+  controller.leaseDuration = director.builder.leaseDuration
+  controller.renewDeadline = director.builder.renewDeadline
+  controller.retryPeriod = director.builder.retryPeriod
+
+  if controller.leaseName == "" {
+    controller.leaseName = controller.controllerName
+  }
+  if controller.leaseNamespace == "" {
+    controller.leaseNamespace = "default"
+  }
+  if controller.leaseDuration == 0 {
+    controller.leaseDuration = 15 * time.Second
+  }
+  if controller.renewDeadline == 0 {
+    controller.renewDeadline = 10 * time.Second
+  }
+  if controller.retryPeriod == 0 {
+    controller.retryPeriod = 2 * time.Second
+  }
+}
+
 // setupKubernetesClients initializes Kubernetes clients for interacting with K8S API,
 // dynamic resources, and CRD resources.
 func (director *controllerDirector) setupKubernetesClients(controller *controller) error {
@@ -184,6 +237,12 @@ func (director *controllerDirector) setupKubernetesClients(controller *controlle
     return fmt.Errorf("failed to create dynamic client: %w", err)
   }
 
+  // Instantiate a cached mapper resolving resource types through discovery.
+  controller.resourceMapper = controllerMisc.NewResourceMapper(controller.k8sClient.Discovery())
+
+  // Lists are merged according to the OpenAPI schemas of the cluster
+  controller.mergeSchemas = controllerMisc.NewMergeSchemas(controller.resourceMapper, controller.k8sClient.Discovery())
+
   // Instantiate a new client for interacting with CRD resources via API calls.
   controller.crdClient, err = crdClientSet.NewForConfig(connectionConfig)
   if err != nil {
@@ -193,76 +252,18 @@ func (director *controllerDirector) setupKubernetesClients(controller *controlle
   return nil
 }
 
-// setupDynamicInformer sets up dynamic informers for the specified resources,
-// watches resources like pods, persistent volume claims, and config maps, and sets up event handlers.
+// setupDynamicInformer prepares the dynamic informers watching the child
+// resources, they are started on demand for the resources rendered by the
+// overlays.
 func (director *controllerDirector) setupDynamicInformer(controller *controller) error {
-    // Define GVRs (GroupVersionResources) for resources to watch
-    resourcesToWatch := []schema.GroupVersionResource{
-        {Group: "", Version: "v1", Resource: "pods"},
-        {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
-        {Group: "", Version: "v1", Resource: "configmaps"},
-    }
-
-    // Setup namespaceFilter
-    namespaceFilter := "" 
-    if director.builder.namespaceFilter != "" {
-      namespaceFilter = director.builder.namespaceFilter
-    }
-
-    // Create a dynamic informer factory
-    informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(
-        controller.dynClient,
-        time.Minute,     // Resync period of 1 minute
-        namespaceFilter, // No namespace filter, watch across all namespaces
-        nil,             // No label selector filter
-    )
-
-    // Slice to store the dynamic informers
-    dynInformers := []cache.SharedInformer{}
-
-    // Create informers for each resource and set up event handlers
-    for _, gvr := range resourcesToWatch {
-        // Get the informer for the current resource type
-        informer := informerFactory.ForResource(gvr).Informer()
-
-        // Append the informer to the list of dynamic informers
-        dynInformers = append(dynInformers, informer)
-
-        // Set event handlers for add, update, and delete events
-        informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-            // handleObject handles add events
-            AddFunc: controller.handleObject,
-
-            // UpdateFunc compares old and new objects to avoid processing if unchanged
-            UpdateFunc: func(oldObj, newObj interface{}) {
-                oldObject := oldObj.(*unstructured.Unstructured)
-                newObject := newObj.(*unstructured.Unstructured)
-
-                // If the resource version is the same, there's no meaningful update
-                if oldObject.GetResourceVersion() == newObject.GetResourceVersion() {
-                    return // No meaningful change; skip processing
-                }
-
-                // Handle the update event (e.g., call a method on the controller)
-                controller.handleObject(newObject)
-            },
-
-            // handleObject handles delete events
-            DeleteFunc: controller.handleObject,
-        })
-
-        // Start the informer asynchronously
-        go informer.Run(controller.workingContext.Done())
-    }
-
-    // Store the informers in the controller
-    controller.dynInformers = dynInformers
-
+    controller.namespaceFilter = director.builder.namespaceFilter
+    controller.resourceWatches = map[schema.GroupVersionResource]*resourceWatch{}
+    controller.watchUsers = map[cache.ObjectName]map[schema.GroupVersionResource]bool{}
     return nil
 }
 
-// setupCustomResourceInformer sets up the informer for custom resources
-// (overlays),
+// setupCustomResourceInformer sets up the informers for custom resources
+// (overlays and overlay sources),
 // adds event handlers for resource events (Add, Update, Delete), and starts the informer factory.
 func (director *controllerDirector) setupCustomResourceInformer(controller *controller) error {
 	// Create a shared informer factory with a 30-second resync period
@@ -274,18 +275,53 @@ func (director *controllerDirector) setupCustomResourceInformer(controller *cont
 	// Get the informer for the "Overlays" custom resource
 	crdInformer := crdInformerFactory.Kubeforge().V1().Overlays()
 
-	// Add event handler for custom resources (Overlays)
+	// Add event handler for custom resources (Overlays), the overlays
+	// inheriting from a changed overlay are enqueued as well
 	crdInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: controller.enqueue,
+		AddFunc: func(obj interface{}) {
+			controller.enqueue(obj)
+			controller.handleOverlayBase(obj)
+		},
 		UpdateFunc: func(old, new interface{}) {
 			controller.enqueue(new)
+			if overlayGenerationChanged(old, new) {
+				controller.handleOverlayBase(new)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			controller.enqueue(obj)
+			controller.handleOverlayBase(obj)
+		},
+	})
+
+	// Index the overlays by the OverlaySource and the base overlays they
+	// reference
+	err := crdInformer.Informer().AddIndexers(cache.Indexers{
+		overlaySourceIndex: overlaySourceIndexFunc,
+		overlayBaseIndex:   overlayBaseIndexFunc,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add overlay indexes: %w", err)
+	}
+
+	// Get the informer for the "OverlaySources" custom resource
+	crdSourceInformer := crdInformerFactory.Kubeforge().V1().OverlaySources()
+
+	// Enqueue the overlays referencing a changed OverlaySource
+	crdSourceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleOverlaySource,
+		UpdateFunc: func(old, new interface{}) {
+			controller.handleOverlaySource(new)
 		},
-		DeleteFunc: controller.enqueue,
+		DeleteFunc: controller.handleOverlaySource,
 	})
 
 	// Pass the lister and sync checker to the controller
 	controller.crdLister = crdInformer.Lister()
+	controller.crdIndexer = crdInformer.Informer().GetIndexer()
 	controller.crdsSynced = crdInformer.Informer().HasSynced
+	controller.crdSourceLister = crdSourceInformer.Lister()
+	controller.crdSourcesSynced = crdSourceInformer.Informer().HasSynced
 
 	// Start the informer factory in the background and wait for it to sync
 	go crdInformerFactory.Start(director.builder.workingContext.Done())
@@ -0,0 +1,157 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Cluster-scoped children can not be garbage collected through an
+// OwnerReference to their namespaced Overlay. An Overlay rendering
+// cluster-scoped resources gets a finalizer, before any of them is
+// applied, and when it is deleted the cluster-scoped resources of
+// its inventory still owned by it are deleted before the finalizer
+// is removed. Namespaced children are left to the garbage collector.
+// An Overlay turned abstract prunes its inventory and drops the
+// finalizer the same way.
+//
+// ############################################################
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+	"kubeforge/internal/k8s/render"
+)
+
+// overlayFinalizer guards the deletion of cluster-scoped children.
+const overlayFinalizer = "kubeforge.sh/cluster-resources"
+
+// hasFinalizer reports whether the overlay carries the finalizer.
+func hasFinalizer(crdOverlay *crdv1.Overlay) bool {
+  for _, finalizer := range crdOverlay.Finalizers {
+    if finalizer == overlayFinalizer {
+      return true
+    }
+  }
+  return false
+}
+
+// hasClusterScopedResources reports whether any of the resources is
+// cluster-scoped.
+func hasClusterScopedResources(resources []render.Resource) bool {
+  for _, resource := range resources {
+    if !resource.Namespaced {
+      return true
+    }
+  }
+  return false
+}
+
+// ensureFinalizer adds the finalizer to the overlay, a copy owned by the
+// caller, whose metadata is updated in place.
+func (controller *controller) ensureFinalizer(
+  ctx        context.Context,
+  crdOverlay *crdv1.Overlay,
+  logger     klog.Logger,
+) error {
+
+    if hasFinalizer(crdOverlay) {
+      return nil
+    }
+
+    logger.Info("Adding finalizer", "finalizer", overlayFinalizer)
+    patched, err := controller.patchFinalizers(ctx, crdOverlay, append(crdOverlay.Finalizers, overlayFinalizer))
+    if err != nil {
+      return err
+    }
+    crdOverlay.ObjectMeta = patched.ObjectMeta
+    return nil
+}
+
+// finalizeOverlay deletes the cluster-scoped children of a deleted overlay
+// and then removes its finalizer.
+func (controller *controller) finalizeOverlay(
+  ctx        context.Context,
+  crdOverlay *crdv1.Overlay,
+  logger     klog.Logger,
+) error {
+
+    if !hasFinalizer(crdOverlay) {
+      return nil
+    }
+
+    for _, resource := range crdOverlay.Status.Resources {
+      if resource.Namespace != "" {
+        continue
+      }
+      if err := controller.pruneResource(ctx, crdOverlay, resource, logger); err != nil {
+        return fmt.Errorf("failed to delete cluster-scoped %s %q: %w", resource.Kind, resource.Name, err)
+      }
+    }
+
+    _, err := controller.removeFinalizer(ctx, crdOverlay, logger)
+    return err
+}
+
+// removeFinalizer removes the finalizer from the overlay and returns the
+// patched overlay, the overlay itself when it has no finalizer.
+func (controller *controller) removeFinalizer(
+  ctx        context.Context,
+  crdOverlay *crdv1.Overlay,
+  logger     klog.Logger,
+) (
+  *crdv1.Overlay,
+  error,
+) {
+
+    if !hasFinalizer(crdOverlay) {
+      return crdOverlay, nil
+    }
+
+    finalizers := []string{}
+    for _, finalizer := range crdOverlay.Finalizers {
+      if finalizer != overlayFinalizer {
+        finalizers = append(finalizers, finalizer)
+      }
+    }
+
+    logger.Info("Removing finalizer", "finalizer", overlayFinalizer)
+    return controller.patchFinalizers(ctx, crdOverlay, finalizers)
+}
+
+// patchFinalizers replaces the finalizers of the overlay, the patch fails if
+// the overlay changed in the meantime.
+func (controller *controller) patchFinalizers(
+  ctx        context.Context,
+  crdOverlay *crdv1.Overlay,
+  finalizers []string,
+) (
+  *crdv1.Overlay,
+  error,
+) {
+
+    patch, err := json.Marshal(map[string]interface{}{
+      "metadata": map[string]interface{}{
+        "finalizers":      finalizers,
+        "resourceVersion": crdOverlay.ResourceVersion,
+      },
+    })
+    if err != nil {
+      return nil, err
+    }
+
+    requestCtx, cancel := controller.requestContext(ctx)
+    defer cancel()
+
+    return controller.crdClient.
+      KubeforgeV1().
+      Overlays(crdOverlay.Namespace).
+      Patch(requestCtx, crdOverlay.Name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: controller.controllerName})
+}
@@ -5,8 +5,9 @@
 //
 // The `handleObject` function processes incoming resources, checking
 // for appropriate OwnerReferences to determine if they are part of
-// the desired CRD. If so, it enqueues the associated Overlay for
-// further handling. The `enqueue` function converts the CRD resource
+// the desired CRD, or for the ownership label of cluster-scoped
+// objects. If so, it enqueues the associated Overlay for further
+// handling. The `enqueue` function converts the CRD resource
 // into a namespace/name string and adds it to the work queue for processing.
 //
 // ############################################################
@@ -14,12 +15,13 @@
 package controller
 
 import (
-	"context"
 	"fmt"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
 )
 
 // handleObject will take any resource implementing metav1.Object and attempt
@@ -30,7 +32,7 @@ import (
 func (controller *controller) handleObject(obj interface{}) {
 	var object metav1.Object
 	var ok bool
-	logger := klog.FromContext(context.Background())
+	logger := klog.FromContext(controller.workingContext)
 
 	if object, ok = obj.(metav1.Object); !ok {
 		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
@@ -39,7 +41,7 @@ func (controller *controller) handleObject(obj interface{}) {
 			// If the object value is not too big and does not contain sensitive information then
 			// it may be useful to include it.
 			runtime.HandleErrorWithContext(
-        context.Background(), 
+        controller.workingContext, 
         nil, 
         "Error decoding object, invalid type", 
         "type", 
@@ -52,7 +54,7 @@ func (controller *controller) handleObject(obj interface{}) {
 			// If the object value is not too big and does not contain sensitive information then
 			// it may be useful to include it.
 			runtime.HandleErrorWithContext(
-        context.Background(), 
+        controller.workingContext, 
         nil, 
         "Error decoding object tombstone, invalid type", 
         "type", 
@@ -90,6 +92,16 @@ func (controller *controller) handleObject(obj interface{}) {
 		controller.enqueue(instance)
 		return
 	}
+
+	// Cluster-scoped objects record their overlay in a label and annotation
+	if overlayName, overlayUID, ok := controllerMisc.GetOverlayOf(object); ok {
+		instance, err := controller.crdLister.Overlays(overlayName.Namespace).Get(overlayName.Name)
+		if err != nil || instance.UID != overlayUID {
+			logger.V(4).Info("Ignore orphaned object", "object", klog.KObj(object), "instance", overlayName)
+			return
+		}
+		controller.enqueue(instance)
+	}
 }
 
 // enqueue takes a CRD resource and converts it into a namespace/name
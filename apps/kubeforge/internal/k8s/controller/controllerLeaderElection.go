@@ -0,0 +1,90 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// With leader election enabled only the replica holding the Lease
+// runs the workers, the other replicas keep their informer caches
+// warm and take over as soon as the Lease expires. Readiness
+// follows leadership. A replica losing the Lease stops with an
+// error, so it restarts as a clean follower.
+//
+// ############################################################
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runWithLeaderElection runs the controller while holding the Lease. It
+// blocks until the working context is done or the leadership is lost.
+func (controller *controller) runWithLeaderElection() error {
+  logger := klog.FromContext(controller.workingContext)
+
+  hostname, err := os.Hostname()
+  if err != nil {
+    return fmt.Errorf("failed to get hostname for leader election: %w", err)
+  }
+  identity := hostname + "_" + string(uuid.NewUUID())
+
+  lock := &resourcelock.LeaseLock{
+    LeaseMeta: metav1.ObjectMeta{
+      Name:      controller.leaseName,
+      Namespace: controller.leaseNamespace,
+    },
+    Client: controller.k8sClient.CoordinationV1(),
+    LockConfig: resourcelock.ResourceLockConfig{
+      Identity: identity,
+    },
+  }
+
+  var runErr error
+  leaderContext, cancel := context.WithCancel(controller.workingContext)
+  defer cancel()
+
+  logger.Info("Waiting for leadership", "lease", klog.KRef(controller.leaseNamespace, controller.leaseName), "identity", identity)
+  leaderelection.RunOrDie(leaderContext, leaderelection.LeaderElectionConfig{
+    Lock:            lock,
+    LeaseDuration:   controller.leaseDuration,
+    RenewDeadline:   controller.renewDeadline,
+    RetryPeriod:     controller.retryPeriod,
+    ReleaseOnCancel: true,
+    Name:            controller.controllerName,
+    Callbacks: leaderelection.LeaderCallbacks{
+      OnStartedLeading: func(ctx context.Context) {
+        logger.Info("Started leading")
+        if err := controller.run(ctx); err != nil {
+          runtime.HandleErrorWithContext(ctx, err, "Controller stopped while leading")
+        }
+
+        // Release the Lease once the workers are done
+        cancel()
+      },
+      OnStoppedLeading: func() {
+        controller.updateReadyz(false)
+        if controller.workingContext.Err() == nil {
+          runErr = fmt.Errorf("leader election lost")
+        }
+        logger.Info("Stopped leading")
+      },
+      OnNewLeader: func(currentIdentity string) {
+        if currentIdentity != identity {
+          logger.Info("New leader elected", "identity", currentIdentity)
+        }
+      },
+    },
+  })
+
+  return runErr
+}
@@ -0,0 +1,66 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Prometheus metrics of the controller, registered in the default
+// registry served by the `/metrics` endpoint.
+//
+// ############################################################
+
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// sourceConfigurationInfo exposes the hash of the loaded source configuration
+	sourceConfigurationInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubeforge",
+			Name:      "source_configuration_info",
+			Help:      "Hash of the loaded source configuration, the value is always 1.",
+		},
+		[]string{"hash"},
+	)
+
+	// sourceConfigurationReloads counts the source configuration (re)loads
+	sourceConfigurationReloads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubeforge",
+			Name:      "source_configuration_reloads_total",
+			Help:      "Number of source configuration loads by result.",
+		},
+		[]string{"result"},
+	)
+
+	// driftedResources counts the resources of an overlay left drifted
+	driftedResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubeforge",
+			Name:      "drifted_resources",
+			Help:      "Number of resources of an overlay changed outside of it and left as they are.",
+		},
+		[]string{"namespace", "overlay"},
+	)
+
+	// driftDetections counts the resources found drifted
+	driftDetections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubeforge",
+			Name:      "drift_detections_total",
+			Help:      "Number of resources found changed outside of their overlay by drift policy.",
+		},
+		[]string{"policy"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		sourceConfigurationInfo,
+		sourceConfigurationReloads,
+		driftedResources,
+		driftDetections,
+	)
+}
@@ -0,0 +1,142 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// An Overlay may inherit the data of base Overlays referenced through
+// `spec.bases` (see `render.Bases`). Overlays are indexed by the
+// bases they reference, so a changed base enqueues the Overlays
+// depending on it, and the ones depending on those in turn.
+//
+// An Overlay marked `spec.abstract: true` is only a base. Turning an
+// Overlay abstract prunes its inventory and drops its finalizer, as
+// deleting it would, unless pruning is off, which is refused while
+// the inventory is not empty.
+//
+// ############################################################
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+)
+
+// overlayBaseIndex indexes overlays by the `namespace/name` of the base
+// overlays they reference.
+const overlayBaseIndex = "overlayBase"
+
+// overlayBaseIndexFunc is the cache.IndexFunc of overlayBaseIndex.
+func overlayBaseIndexFunc(obj interface{}) ([]string, error) {
+  crdOverlay, ok := obj.(*crdv1.Overlay)
+  if !ok {
+    return nil, nil
+  }
+  keys := make([]string, 0, len(crdOverlay.Spec.Bases))
+  for _, base := range crdOverlay.Spec.Bases {
+    keys = append(keys, crdOverlay.Namespace+"/"+base.Name)
+  }
+  return keys, nil
+}
+
+// handleOverlayBase enqueues the overlays inheriting from the given overlay,
+// directly or through other bases.
+func (controller *controller) handleOverlayBase(obj interface{}) {
+  if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+    obj = tombstone.Obj
+  }
+  crdOverlay, ok := obj.(*crdv1.Overlay)
+  if !ok {
+    runtime.HandleError(fmt.Errorf("unexpected object type %T, expected Overlay", obj))
+    return
+  }
+
+  // Walk the dependents breadth first, a cycle is reported by their sync
+  visited := map[string]bool{crdOverlay.Namespace + "/" + crdOverlay.Name: true}
+  pending := []string{crdOverlay.Namespace + "/" + crdOverlay.Name}
+  for len(pending) > 0 {
+    key := pending[0]
+    pending = pending[1:]
+
+    dependents, err := controller.crdIndexer.ByIndex(overlayBaseIndex, key)
+    if err != nil {
+      runtime.HandleError(err)
+      return
+    }
+    for _, dependent := range dependents {
+      dependentOverlay, ok := dependent.(*crdv1.Overlay)
+      if !ok {
+        continue
+      }
+      dependentKey := dependentOverlay.Namespace + "/" + dependentOverlay.Name
+      if visited[dependentKey] {
+        continue
+      }
+      visited[dependentKey] = true
+      pending = append(pending, dependentKey)
+      controller.enqueue(dependentOverlay)
+    }
+  }
+}
+
+// getBaseOverlay is the render.OverlayGetter of the controller, reading the
+// base overlays from the informer cache.
+func (controller *controller) getBaseOverlay(namespace, name string) (*crdv1.Overlay, error) {
+  return controller.crdLister.Overlays(namespace).Get(name)
+}
+
+// overlayGenerationChanged reports whether the spec of an overlay changed,
+// status updates of a base leave the overlays inheriting from it alone.
+func overlayGenerationChanged(old, new interface{}) bool {
+  oldOverlay, ok := old.(*crdv1.Overlay)
+  if !ok {
+    return true
+  }
+  newOverlay, ok := new.(*crdv1.Overlay)
+  if !ok {
+    return true
+  }
+  return oldOverlay.Generation != newOverlay.Generation
+}
+
+// syncAbstractOverlay prunes the inventory of an abstract overlay and
+// removes its finalizer, the remaining inventory is written to the given
+// status.
+func (controller *controller) syncAbstractOverlay(
+  ctx        context.Context,
+  crdOverlay *crdv1.Overlay,
+  status     *crdv1.OverlayStatus,
+  logger     klog.Logger,
+) (
+  syncResult,
+  error,
+) {
+
+    result := syncResult{abstract: true}
+
+    // Resources left behind would no longer be reconciled nor cleaned up
+    if !overlayPruneEnabled(crdOverlay) && len(crdOverlay.Status.Resources) > 0 {
+      result.abstractResources = len(crdOverlay.Status.Resources)
+      return result, nil
+    }
+
+    inventory, err := controller.pruneResources(ctx, crdOverlay, nil, true, logger)
+    status.Resources = inventory
+    if err != nil {
+      return result, err
+    }
+    status.LastAppliedHash = ""
+
+    patched, err := controller.removeFinalizer(ctx, crdOverlay, logger)
+    if err != nil {
+      return result, err
+    }
+    crdOverlay.ObjectMeta = patched.ObjectMeta
+    return result, nil
+}
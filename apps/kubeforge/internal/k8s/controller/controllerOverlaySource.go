@@ -0,0 +1,81 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// An Overlay may render on top of a OverlaySource, referenced by
+// name through `spec.sourceRef`, instead of the source configuration
+// file of the controller. Overlays are indexed by the source they
+// reference, so a changed OverlaySource only enqueues the Overlays
+// depending on it.
+//
+// ############################################################
+
+package controller
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+)
+
+// overlaySourceIndex indexes overlays by the `namespace/name` of the
+// OverlaySource they reference.
+const overlaySourceIndex = "overlaySource"
+
+// overlaySourceIndexFunc is the cache.IndexFunc of overlaySourceIndex.
+func overlaySourceIndexFunc(obj interface{}) ([]string, error) {
+  crdOverlay, ok := obj.(*crdv1.Overlay)
+  if !ok || crdOverlay.Spec.SourceRef == nil {
+    return nil, nil
+  }
+  return []string{crdOverlay.Namespace + "/" + crdOverlay.Spec.SourceRef.Name}, nil
+}
+
+// handleOverlaySource enqueues the overlays referencing the given
+// OverlaySource.
+func (controller *controller) handleOverlaySource(obj interface{}) {
+  if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+    obj = tombstone.Obj
+  }
+  crdSource, ok := obj.(*crdv1.OverlaySource)
+  if !ok {
+    runtime.HandleError(fmt.Errorf("unexpected object type %T, expected OverlaySource", obj))
+    return
+  }
+
+  crdOverlays, err := controller.crdIndexer.ByIndex(overlaySourceIndex, crdSource.Namespace+"/"+crdSource.Name)
+  if err != nil {
+    runtime.HandleError(err)
+    return
+  }
+  for _, crdOverlay := range crdOverlays {
+    controller.enqueue(crdOverlay)
+  }
+}
+
+// getOverlaySourceConfiguration returns the source configuration an overlay
+// renders on top of and its hash, the referenced OverlaySource or the
+// loaded source configuration file.
+func (controller *controller) getOverlaySourceConfiguration(crdOverlay *crdv1.Overlay) (string, string, error) {
+  if crdOverlay.Spec.SourceRef == nil {
+    content, hash := controller.getSourceConfiguration()
+    return content, hash, nil
+  }
+
+  crdSource, err := controller.crdSourceLister.OverlaySources(crdOverlay.Namespace).Get(crdOverlay.Spec.SourceRef.Name)
+  if errors.IsNotFound(err) {
+    return "", "", fmt.Errorf("overlay source %q not found", crdOverlay.Spec.SourceRef.Name)
+  }
+  if err != nil {
+    return "", "", err
+  }
+
+  content := string(crdSource.Spec.Data.Raw)
+  return content, fmt.Sprintf("%x", sha256.Sum256([]byte(content))), nil
+}
@@ -0,0 +1,141 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Every resource applied by an Overlay is recorded in its
+// `status.resources` inventory. On each reconcile `pruneResources`
+// compares the inventory with the resources rendered this time and
+// deletes the ones the Overlay no longer mentions. Pruning can be
+// turned off per Overlay with `spec.prune: false`.
+//
+// ############################################################
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
+)
+
+// overlayPruneEnabled reports whether removed resources should be deleted.
+func overlayPruneEnabled(crdOverlay *crdv1.Overlay) bool {
+  return crdOverlay.Spec.Prune == nil || *crdOverlay.Spec.Prune
+}
+
+// inventoryKey identifies an inventory entry independently of its version.
+func inventoryKey(resource crdv1.OverlayResource) string {
+  return fmt.Sprintf("%s/%s/%s/%s", resource.Group, resource.Kind, resource.Namespace, resource.Name)
+}
+
+// pruneResources deletes the resources from the overlay inventory that are
+// not part of the rendered ones and returns the inventory to record. When
+// pruning is disabled, or not allowed for this reconcile, the previous
+// entries are kept so a later reconcile still cleans them up.
+func (controller *controller) pruneResources(
+  ctx        context.Context,
+  crdOverlay *crdv1.Overlay,
+  rendered   []crdv1.OverlayResource,
+  allowPrune bool,
+  logger     klog.Logger,
+) (
+  []crdv1.OverlayResource,
+  error,
+) {
+
+    renderedKeys := map[string]bool{}
+    for _, resource := range rendered {
+      renderedKeys[inventoryKey(resource)] = true
+    }
+
+    inventory := append([]crdv1.OverlayResource{}, rendered...)
+    for _, resource := range crdOverlay.Status.Resources {
+      if renderedKeys[inventoryKey(resource)] {
+        continue
+      }
+
+      if !allowPrune || !overlayPruneEnabled(crdOverlay) {
+        inventory = append(inventory, resource)
+        continue
+      }
+
+      if err := controller.pruneResource(ctx, crdOverlay, resource, logger); err != nil {
+        // Keep the entry so the deletion is retried on the next reconcile
+        inventory = append(inventory, resource)
+        sortInventory(inventory)
+        return inventory, err
+      }
+    }
+
+    sortInventory(inventory)
+    return inventory, nil
+}
+
+// sortInventory orders the inventory so it does not depend on the map
+// iteration order used while rendering.
+func sortInventory(inventory []crdv1.OverlayResource) {
+  sort.Slice(inventory, func(i, j int) bool {
+    return inventoryKey(inventory[i]) < inventoryKey(inventory[j])
+  })
+}
+
+// pruneResource deletes a single resource, as long as it is still controlled
+// by the overlay.
+func (controller *controller) pruneResource(
+  ctx        context.Context,
+  crdOverlay *crdv1.Overlay,
+  resource   crdv1.OverlayResource,
+  logger     klog.Logger,
+) error {
+
+    logger = logger.WithValues("kind", resource.Kind, "resource", klog.KRef(resource.Namespace, resource.Name))
+
+    kind := schema.GroupVersionKind{Group: resource.Group, Version: resource.Version, Kind: resource.Kind}
+    mapping, err := controller.resourceMapper.MappingForKind(kind)
+    if err != nil {
+      logger.Error(err, "Failed to resolve resource to prune")
+      return err
+    }
+
+    resourceClient := controller.dynClient.Resource(mapping.Resource).Namespace(resource.Namespace)
+    requestCtx, cancel := controller.requestContext(ctx)
+    existingResource, err := resourceClient.Get(requestCtx, resource.Name, metav1.GetOptions{})
+    cancel()
+    if errors.IsNotFound(err) {
+      return nil
+    }
+    if err != nil {
+      return err
+    }
+
+    // Never delete a resource that was adopted by someone else in the meantime
+    if !controllerMisc.IsOwnedBy(existingResource, crdOverlay) {
+      logger.Info("Skip pruning resource not controlled by the overlay")
+      return nil
+    }
+
+    propagationPolicy := metav1.DeletePropagationBackground
+    requestCtx, cancel = controller.requestContext(ctx)
+    err = resourceClient.Delete(requestCtx, resource.Name, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
+    cancel()
+    if err != nil && !errors.IsNotFound(err) {
+      logger.Error(err, "Failed to prune resource")
+      return err
+    }
+
+    logger.Info("Resource pruned")
+    controller.recorder.Eventf(crdOverlay, corev1.EventTypeNormal, string(resourceActionPruned), "%s %q pruned", resource.Kind, resource.Name)
+    return nil
+}
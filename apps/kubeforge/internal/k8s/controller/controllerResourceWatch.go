@@ -0,0 +1,144 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Child resources are watched by dynamic informers started on
+// demand. Each reconcile records the resources rendered by the
+// Overlay; an informer is started for every resource a first
+// Overlay renders and stopped once no Overlay renders it any more.
+// Events of watched objects enqueue their owning Overlay through
+// `handleObject`.
+//
+// ############################################################
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kubeforge/internal/k8s/render"
+)
+
+// dynamicResyncPeriod is the resync period of the child resource informers.
+const dynamicResyncPeriod = time.Minute
+
+// resourceWatch is a running informer of a child resource.
+type resourceWatch struct {
+  informer cache.SharedIndexInformer
+  cancel   context.CancelFunc
+}
+
+// updateResourceWatches records the resources rendered by an overlay, starts
+// the informers missing for them and stops the ones no longer used.
+func (controller *controller) updateResourceWatches(
+  obj       cache.ObjectName,
+  resources []render.Resource,
+  logger    klog.Logger,
+) {
+
+    used := map[schema.GroupVersionResource]bool{}
+    for _, resource := range resources {
+      used[resource.Schema] = resource.Namespaced
+    }
+
+    controller.watchMutex.Lock()
+    defer controller.watchMutex.Unlock()
+
+    if len(used) == 0 {
+      delete(controller.watchUsers, obj)
+    } else {
+      controller.watchUsers[obj] = used
+    }
+
+    for gvr, namespaced := range used {
+      if _, exists := controller.resourceWatches[gvr]; !exists {
+        controller.startResourceWatch(gvr, namespaced, logger)
+      }
+    }
+    controller.stopUnusedResourceWatches(logger)
+}
+
+// releaseResourceWatches forgets the resources of a deleted overlay and stops
+// the informers no longer used.
+func (controller *controller) releaseResourceWatches(obj cache.ObjectName, logger klog.Logger) {
+  controller.watchMutex.Lock()
+  defer controller.watchMutex.Unlock()
+
+  delete(controller.watchUsers, obj)
+  controller.stopUnusedResourceWatches(logger)
+}
+
+// startResourceWatch starts the informer of a resource, the caller holds the
+// watch mutex.
+func (controller *controller) startResourceWatch(
+  gvr        schema.GroupVersionResource,
+  namespaced bool,
+  logger     klog.Logger,
+) {
+
+    // The namespace filter only applies to namespaced resources
+    namespace := metav1.NamespaceAll
+    if namespaced {
+      namespace = controller.namespaceFilter
+    }
+
+    informer := dynamicinformer.NewFilteredDynamicInformer(
+      controller.dynClient,
+      gvr,
+      namespace,
+      dynamicResyncPeriod,
+      cache.Indexers{},
+      nil,
+    ).Informer()
+
+    informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+      AddFunc: controller.handleObject,
+      UpdateFunc: func(oldObj, newObj interface{}) {
+        oldObject := oldObj.(*unstructured.Unstructured)
+        newObject := newObj.(*unstructured.Unstructured)
+
+        // If the resource version is the same, there's no meaningful update
+        if oldObject.GetResourceVersion() == newObject.GetResourceVersion() {
+          return
+        }
+        controller.handleObject(newObject)
+      },
+      DeleteFunc: controller.handleObject,
+    })
+
+    watchContext, cancel := context.WithCancel(controller.workingContext)
+    go informer.Run(watchContext.Done())
+
+    controller.resourceWatches[gvr] = &resourceWatch{informer: informer, cancel: cancel}
+    logger.Info("Started watching resource", "resource", gvr.String())
+}
+
+// stopUnusedResourceWatches stops the informers of resources no overlay
+// renders, the caller holds the watch mutex.
+func (controller *controller) stopUnusedResourceWatches(logger klog.Logger) {
+  used := map[schema.GroupVersionResource]bool{}
+  for _, resources := range controller.watchUsers {
+    for gvr := range resources {
+      used[gvr] = true
+    }
+  }
+
+  for gvr, watch := range controller.resourceWatches {
+    if used[gvr] {
+      continue
+    }
+    watch.cancel()
+    delete(controller.resourceWatches, gvr)
+    logger.Info("Stopped watching resource", "resource", gvr.String())
+  }
+}
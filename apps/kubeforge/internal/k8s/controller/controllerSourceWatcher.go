@@ -0,0 +1,139 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// The source configuration file is loaded once at startup and then
+// watched with fsnotify. The parent directory is watched rather than
+// the file itself, so the symlink swap done by the kubelet when a
+// mounted ConfigMap changes (`..data` pointing to a new directory)
+// is noticed as well. A changed file is validated first and, when
+// valid, replaces the loaded configuration and every Overlay is
+// enqueued again. Invalid content is reported and ignored, the last
+// valid configuration stays in use.
+//
+// ############################################################
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+
+	"kubeforge/internal/k8s/render"
+	"kubeforge/internal/ops/file"
+)
+
+// sourceConfigurationSymlink is the symlink swapped by the kubelet when a
+// mounted ConfigMap is updated.
+const sourceConfigurationSymlink = "..data"
+
+// getSourceConfiguration returns the loaded source configuration and its hash.
+func (controller *controller) getSourceConfiguration() (string, string) {
+  controller.sourceMutex.RLock()
+  defer controller.sourceMutex.RUnlock()
+  return controller.sourceContent, controller.sourceHash
+}
+
+// loadSourceConfiguration reads and validates the source configuration file,
+// it reports whether the loaded configuration changed.
+func (controller *controller) loadSourceConfiguration(logger klog.Logger) (bool, error) {
+
+    fileContent, err := file.Read(controller.sourceConfiguration, file.ReadModeString)
+    if err != nil {
+      sourceConfigurationReloads.WithLabelValues("failure").Inc()
+      return false, err
+    }
+    content := fileContent.(string)
+
+    // Validate the content before it replaces the loaded one
+    if _, err := render.UnmarshalSource(content, controller.environment.Source); err != nil {
+      sourceConfigurationReloads.WithLabelValues("failure").Inc()
+      return false, fmt.Errorf("invalid source configuration: %w", err)
+    }
+
+    hash := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+
+    controller.sourceMutex.Lock()
+    changed := hash != controller.sourceHash
+    controller.sourceContent = content
+    controller.sourceHash = hash
+    controller.sourceMutex.Unlock()
+
+    if changed {
+      logger.Info("Source configuration loaded", "path", controller.sourceConfiguration, "hash", hash)
+      sourceConfigurationReloads.WithLabelValues("success").Inc()
+      sourceConfigurationInfo.Reset()
+      sourceConfigurationInfo.WithLabelValues(hash).Set(1)
+    }
+    return changed, nil
+}
+
+// watchSourceConfiguration reloads the source configuration whenever its
+// file changes and enqueues every overlay. It blocks until ctx is done.
+func (controller *controller) watchSourceConfiguration(ctx context.Context) error {
+    logger := klog.FromContext(ctx)
+
+    watcher, err := fsnotify.NewWatcher()
+    if err != nil {
+      return fmt.Errorf("failed to create source configuration watcher: %w", err)
+    }
+    defer watcher.Close()
+
+    sourcePath := filepath.Clean(controller.sourceConfiguration)
+    if err := watcher.Add(filepath.Dir(sourcePath)); err != nil {
+      return fmt.Errorf("failed to watch source configuration: %w", err)
+    }
+
+    for {
+      select {
+      case <-ctx.Done():
+        return nil
+
+      case event, ok := <-watcher.Events:
+        if !ok {
+          return nil
+        }
+        if filepath.Clean(event.Name) != sourcePath && filepath.Base(event.Name) != sourceConfigurationSymlink {
+          continue
+        }
+
+        changed, err := controller.loadSourceConfiguration(logger)
+        if err != nil {
+          runtime.HandleErrorWithContext(ctx, err, "Failed to reload source configuration, keeping the previous one")
+          continue
+        }
+        if changed {
+          controller.enqueueAllOverlays(logger)
+        }
+
+      case err, ok := <-watcher.Errors:
+        if !ok {
+          return nil
+        }
+        runtime.HandleErrorWithContext(ctx, err, "Source configuration watcher failed")
+      }
+    }
+}
+
+// enqueueAllOverlays enqueues every overlay known by the informer.
+func (controller *controller) enqueueAllOverlays(logger klog.Logger) {
+  crdOverlays, err := controller.crdLister.List(labels.Everything())
+  if err != nil {
+    runtime.HandleError(err)
+    return
+  }
+
+  logger.Info("Enqueue all overlays", "count", len(crdOverlays))
+  for _, crdOverlay := range crdOverlays {
+    controller.enqueue(crdOverlay)
+  }
+}
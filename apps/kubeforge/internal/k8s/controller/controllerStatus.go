@@ -0,0 +1,290 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// The Overlay status reports the outcome of each reconcile. It
+// carries the `Ready`, `Reconciling`, `Stalled` and `Drifted`
+// conditions, the generation they refer to, the hash of the last
+// fully applied render and the per-resource results (inventory).
+// `Ready` is only true once every resource is applied and its kstatus
+// health is `Current`. The status is written through the status
+// subresource, and only when it changed.
+//
+// ############################################################
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+	"kubeforge/internal/k8s/render"
+)
+
+// syncResult summarizes a reconcile for the status conditions.
+type syncResult struct {
+  failed    int  // number of resources which failed to apply
+  conflicts int  // number of those owned by someone else
+  pending   bool // resources were deleted and still need to be recreated
+  drifted   int  // number of resources drifted and left as they are
+  waiting   int  // number of resources waiting for their dependencies
+  abstract  bool // the overlay is only a base and renders nothing
+
+  abstractResources int // inventory an abstract overlay can not prune
+
+  progressing int // number of applied resources not current yet
+  unhealthy   int // number of applied resources failed
+
+  applyErrors []error // errors of the resources which failed to apply
+}
+
+// overlayDriftPolicy returns the drift policy of the overlay.
+func overlayDriftPolicy(crdOverlay *crdv1.Overlay) crdv1.DriftPolicy {
+  if crdOverlay.Spec.DriftPolicy == "" {
+    return crdv1.DriftPolicyEnforce
+  }
+  return crdOverlay.Spec.DriftPolicy
+}
+
+// previousResourceAction returns the action recorded for a resource by the
+// previous reconcile.
+func previousResourceAction(
+  crdOverlay *crdv1.Overlay,
+  kind       schema.GroupVersionKind,
+  namespace  string,
+  name       string,
+) string {
+
+    key := inventoryKey(crdv1.OverlayResource{Group: kind.Group, Kind: kind.Kind, Namespace: namespace, Name: name})
+    for _, previous := range crdOverlay.Status.Resources {
+      if inventoryKey(previous) == key {
+        return previous.Action
+      }
+    }
+    return ""
+}
+
+// overlayResourceResult builds the inventory entry of an applied resource.
+// Unchanged resources keep the last action recorded for them, unless they
+// were drifted or waiting.
+func overlayResourceResult(
+  crdOverlay *crdv1.Overlay,
+  kind       schema.GroupVersionKind,
+  namespace  string,
+  name       string,
+  action     resourceAction,
+  err        error,
+) crdv1.OverlayResource {
+
+    resource := crdv1.OverlayResource{
+      Group:     kind.Group,
+      Version:   kind.Version,
+      Kind:      kind.Kind,
+      Namespace: namespace,
+      Name:      name,
+      Action:    string(action),
+    }
+
+    if err != nil {
+      resource.Action = "Failed"
+      resource.Error = err.Error()
+      return resource
+    }
+
+    if action == resourceActionUnchanged {
+      for _, previous := range crdOverlay.Status.Resources {
+        if inventoryKey(previous) == inventoryKey(resource) && previous.Error == "" && previous.Action != "" && previous.Action != string(resourceActionDrifted) && previous.Action != string(resourceActionWaiting) {
+          resource.Action = previous.Action
+        }
+      }
+    }
+
+    return resource
+}
+
+// waitingResourceResult builds the inventory entry of a resource held back
+// by one of its dependencies.
+func waitingResourceResult(
+  kind       schema.GroupVersionKind,
+  namespace  string,
+  name       string,
+  waitingFor string,
+) crdv1.OverlayResource {
+
+    return crdv1.OverlayResource{
+      Group:     kind.Group,
+      Version:   kind.Version,
+      Kind:      kind.Kind,
+      Namespace: namespace,
+      Name:      name,
+      Action:    string(resourceActionWaiting),
+      Message:   "waiting for " + waitingFor,
+    }
+}
+
+// setOverlayConditions sets the overlay conditions according to the outcome
+// of the reconcile of the given generation.
+func setOverlayConditions(
+  status     *crdv1.OverlayStatus,
+  generation int64,
+  result     syncResult,
+  err        error,
+) {
+
+    status.ObservedGeneration = generation
+
+    ready := metav1.Condition{Type: crdv1.OverlayConditionReady, ObservedGeneration: generation}
+    reconciling := metav1.Condition{Type: crdv1.OverlayConditionReconciling, ObservedGeneration: generation}
+    stalled := metav1.Condition{Type: crdv1.OverlayConditionStalled, ObservedGeneration: generation}
+
+    switch {
+    case err != nil:
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "ReconcileFailed", err.Error()
+      reconciling.Status, reconciling.Reason = metav1.ConditionFalse, "ReconcileFailed"
+      stalled.Status, stalled.Reason, stalled.Message = metav1.ConditionTrue, "ReconcileFailed", err.Error()
+
+    case result.abstractResources > 0:
+      message := fmt.Sprintf("%d resource(s) would be left behind, an abstract overlay needs pruning enabled or an empty inventory", result.abstractResources)
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "AbstractWithResources", message
+      reconciling.Status, reconciling.Reason = metav1.ConditionFalse, "AbstractWithResources"
+      stalled.Status, stalled.Reason, stalled.Message = metav1.ConditionTrue, "AbstractWithResources", message
+
+    // Abstract overlays render nothing, there is nothing to be ready
+    case result.abstract:
+      reconciling.Status, reconciling.Reason = metav1.ConditionFalse, "Abstract"
+      stalled.Status, stalled.Reason = metav1.ConditionFalse, "Abstract"
+      meta.RemoveStatusCondition(&status.Conditions, crdv1.OverlayConditionReady)
+      meta.SetStatusCondition(&status.Conditions, reconciling)
+      meta.SetStatusCondition(&status.Conditions, stalled)
+      return
+
+    case result.conflicts > 0:
+      message := fmt.Sprintf("%d cluster-scoped resource(s) exist and are not owned by the overlay", result.conflicts)
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "OwnershipConflict", message
+      reconciling.Status, reconciling.Reason = metav1.ConditionFalse, "OwnershipConflict"
+      stalled.Status, stalled.Reason, stalled.Message = metav1.ConditionTrue, "OwnershipConflict", message
+
+    case result.failed > 0:
+      message := fmt.Sprintf("%d resource(s) failed to apply", result.failed)
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "ApplyFailed", message
+      reconciling.Status, reconciling.Reason = metav1.ConditionFalse, "ApplyFailed"
+      stalled.Status, stalled.Reason, stalled.Message = metav1.ConditionTrue, "ApplyFailed", message
+
+    case result.waiting > 0:
+      message := fmt.Sprintf("%d resource(s) waiting for their dependencies", result.waiting)
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "WaitingForDependencies", message
+      reconciling.Status, reconciling.Reason, reconciling.Message = metav1.ConditionTrue, "WaitingForDependencies", message
+      stalled.Status, stalled.Reason = metav1.ConditionFalse, "WaitingForDependencies"
+
+    case result.pending:
+      message := "Waiting for recreated resources"
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "Recreating", message
+      reconciling.Status, reconciling.Reason, reconciling.Message = metav1.ConditionTrue, "Recreating", message
+      stalled.Status, stalled.Reason = metav1.ConditionFalse, "Recreating"
+
+    case result.unhealthy > 0:
+      message := fmt.Sprintf("%d resource(s) failed", result.unhealthy)
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "ResourcesFailed", message
+      reconciling.Status, reconciling.Reason = metav1.ConditionFalse, "ResourcesFailed"
+      stalled.Status, stalled.Reason, stalled.Message = metav1.ConditionTrue, "ResourcesFailed", message
+
+    case result.progressing > 0:
+      message := fmt.Sprintf("%d resource(s) in progress", result.progressing)
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "Progressing", message
+      reconciling.Status, reconciling.Reason, reconciling.Message = metav1.ConditionTrue, "Progressing", message
+      stalled.Status, stalled.Reason = metav1.ConditionFalse, "Progressing"
+
+    default:
+      ready.Status, ready.Reason, ready.Message = metav1.ConditionTrue, "Applied", "All resources applied and current"
+      reconciling.Status, reconciling.Reason = metav1.ConditionFalse, "Applied"
+      stalled.Status, stalled.Reason = metav1.ConditionFalse, "Applied"
+    }
+
+    meta.SetStatusCondition(&status.Conditions, ready)
+    meta.SetStatusCondition(&status.Conditions, reconciling)
+    meta.SetStatusCondition(&status.Conditions, stalled)
+}
+
+// setDriftCondition sets the Drifted condition, it is only kept for overlays
+// looking for drift.
+func setDriftCondition(
+  status     *crdv1.OverlayStatus,
+  crdOverlay *crdv1.Overlay,
+  result     syncResult,
+) {
+
+    driftPolicy := overlayDriftPolicy(crdOverlay)
+    if driftPolicy == crdv1.DriftPolicyIgnore {
+      meta.RemoveStatusCondition(&status.Conditions, crdv1.OverlayConditionDrifted)
+      return
+    }
+
+    drifted := metav1.Condition{
+      Type:               crdv1.OverlayConditionDrifted,
+      ObservedGeneration: crdOverlay.Generation,
+      Status:             metav1.ConditionFalse,
+      Reason:             "NoDrift",
+    }
+    if driftPolicy == crdv1.DriftPolicyEnforce {
+      drifted.Reason = "Enforced"
+    }
+    if result.drifted > 0 {
+      drifted.Status = metav1.ConditionTrue
+      drifted.Reason = "Drifted"
+      drifted.Message = fmt.Sprintf("%d resource(s) changed outside of the overlay", result.drifted)
+    }
+
+    meta.SetStatusCondition(&status.Conditions, drifted)
+}
+
+// renderHash returns a stable hash of the rendered resources.
+func renderHash(resources []render.Resource) string {
+  hash := sha256.New()
+  for _, resource := range resources {
+    // Maps are marshaled with sorted keys, so the hash is stable
+    rendered, err := json.Marshal(resource.Object.Object)
+    if err != nil {
+      return ""
+    }
+    hash.Write(rendered)
+  }
+  return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// updateStatus writes the status through the status subresource when it
+// differs from the one of the cached overlay.
+func (controller *controller) updateStatus(
+  ctx        context.Context,
+  crdOverlay *crdv1.Overlay,
+  status     *crdv1.OverlayStatus,
+) error {
+
+    if len(status.Resources) == 0 {
+      status.Resources = nil
+    }
+    if equality.Semantic.DeepEqual(crdOverlay.Status, *status) {
+      return nil
+    }
+
+    crdOverlayCopy := crdOverlay.DeepCopy()
+    crdOverlayCopy.Status = *status
+
+    requestCtx, cancel := controller.requestContext(ctx)
+    defer cancel()
+
+    _, err := controller.crdClient.
+      KubeforgeV1().
+      Overlays(crdOverlay.Namespace).
+      UpdateStatus(requestCtx, crdOverlayCopy, metav1.UpdateOptions{FieldManager: controller.controllerName})
+    return err
+}
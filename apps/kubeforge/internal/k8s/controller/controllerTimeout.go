@@ -0,0 +1,35 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Every reconcile runs under the context of its worker, bounded by
+// the reconcile timeout, and every API request it makes is bounded
+// by the request timeout. A hung API server fails the reconcile
+// (which is requeued) instead of stalling the worker, and shutting
+// down cancels the requests in flight. The status of a timed out
+// reconcile is written under the worker context, so it is recorded.
+//
+// ############################################################
+
+package controller
+
+import (
+	"context"
+)
+
+// reconcileContext returns the context of a single reconcile.
+func (controller *controller) reconcileContext(ctx context.Context) (context.Context, context.CancelFunc) {
+  if controller.reconcileTimeout <= 0 {
+    return context.WithCancel(ctx)
+  }
+  return context.WithTimeout(ctx, controller.reconcileTimeout)
+}
+
+// requestContext returns the context of a single API request.
+func (controller *controller) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+  if controller.requestTimeout <= 0 {
+    return context.WithCancel(ctx)
+  }
+  return context.WithTimeout(ctx, controller.requestTimeout)
+}
@@ -0,0 +1,257 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Health of a live object following the kstatus rules: an object is
+// `Current` once it reached its desired state, `InProgress` while it
+// is getting there and `Failed` when it will not get there without
+// a change. Well known kinds are checked through their status, any
+// other object through its `observedGeneration` and the `Ready`,
+// `Reconciling` and `Stalled` conditions.
+//
+// ############################################################
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Health is the kstatus status of an object.
+type Health string
+
+const (
+  HealthCurrent    Health = "Current"
+  HealthInProgress Health = "InProgress"
+  HealthFailed     Health = "Failed"
+)
+
+// failedWaitingReasons are the container waiting reasons a Pod does not
+// recover from on its own.
+var failedWaitingReasons = map[string]bool{
+  "CrashLoopBackOff":           true,
+  "ImagePullBackOff":           true,
+  "ErrImagePull":               true,
+  "InvalidImageName":           true,
+  "CreateContainerConfigError": true,
+  "CreateContainerError":       true,
+}
+
+// ComputeHealth returns the health of the object and a message explaining
+// it.
+func ComputeHealth(object *unstructured.Unstructured) (Health, string) {
+  if object == nil {
+    return HealthInProgress, "not found"
+  }
+  if object.GetDeletionTimestamp() != nil {
+    return HealthInProgress, "being deleted"
+  }
+
+  // Status of an older generation says nothing about the current one
+  observedGeneration, found, _ := unstructured.NestedInt64(object.Object, "status", "observedGeneration")
+  if found && observedGeneration < object.GetGeneration() {
+    return HealthInProgress, "status not observed yet"
+  }
+
+  switch object.GetKind() {
+  case "Namespace":
+    phase, _, _ := unstructured.NestedString(object.Object, "status", "phase")
+    if phase != "Active" {
+      return HealthInProgress, fmt.Sprintf("phase is %q", phase)
+    }
+    return HealthCurrent, ""
+
+  case "CustomResourceDefinition":
+    if status, _ := condition(object, "NamesAccepted"); status == "False" {
+      return HealthFailed, "names not accepted"
+    }
+    return conditionHealth(object, "Established")
+
+  case "PersistentVolumeClaim":
+    phase, _, _ := unstructured.NestedString(object.Object, "status", "phase")
+    switch phase {
+    case "Bound":
+      return HealthCurrent, ""
+    case "Lost":
+      return HealthFailed, "volume lost"
+    }
+    return HealthInProgress, fmt.Sprintf("phase is %q", phase)
+
+  case "Pod":
+    return podHealth(object)
+
+  case "Deployment":
+    return deploymentHealth(object)
+
+  case "StatefulSet":
+    want := specReplicas(object)
+    if ready := statusInt(object, "readyReplicas"); ready < want {
+      return HealthInProgress, fmt.Sprintf("%d of %d replicas ready", ready, want)
+    }
+    if updated := statusInt(object, "updatedReplicas"); updated < want {
+      return HealthInProgress, fmt.Sprintf("%d of %d replicas updated", updated, want)
+    }
+    return HealthCurrent, ""
+
+  case "ReplicaSet":
+    want := specReplicas(object)
+    if ready := statusInt(object, "readyReplicas"); ready < want {
+      return HealthInProgress, fmt.Sprintf("%d of %d replicas ready", ready, want)
+    }
+    return HealthCurrent, ""
+
+  case "DaemonSet":
+    want := statusInt(object, "desiredNumberScheduled")
+    if updated := statusInt(object, "updatedNumberScheduled"); updated < want {
+      return HealthInProgress, fmt.Sprintf("%d of %d pods updated", updated, want)
+    }
+    if ready := statusInt(object, "numberAvailable"); ready < want {
+      return HealthInProgress, fmt.Sprintf("%d of %d pods available", ready, want)
+    }
+    return HealthCurrent, ""
+
+  case "Job":
+    if status, _ := condition(object, "Failed"); status == "True" {
+      return HealthFailed, conditionMessage(object, "Failed")
+    }
+    return conditionHealth(object, "Complete")
+
+  case "Service":
+    serviceType, _, _ := unstructured.NestedString(object.Object, "spec", "type")
+    ingress, _, _ := unstructured.NestedSlice(object.Object, "status", "loadBalancer", "ingress")
+    if serviceType == "LoadBalancer" && len(ingress) == 0 {
+      return HealthInProgress, "waiting for a load balancer"
+    }
+    return HealthCurrent, ""
+  }
+
+  return genericHealth(object)
+}
+
+// genericHealth follows the standard kstatus conditions.
+func genericHealth(object *unstructured.Unstructured) (Health, string) {
+  if status, _ := condition(object, "Stalled"); status == "True" {
+    return HealthFailed, conditionMessage(object, "Stalled")
+  }
+  if status, _ := condition(object, "Reconciling"); status == "True" {
+    return HealthInProgress, conditionMessage(object, "Reconciling")
+  }
+  if status, found := condition(object, "Ready"); found && status != "True" {
+    return HealthInProgress, conditionMessage(object, "Ready")
+  }
+  return HealthCurrent, ""
+}
+
+// podHealth is Current for a ready or succeeded Pod, Failed for a failed Pod
+// or one stuck on a container it can not start.
+func podHealth(object *unstructured.Unstructured) (Health, string) {
+  phase, _, _ := unstructured.NestedString(object.Object, "status", "phase")
+  switch phase {
+  case "Succeeded":
+    return HealthCurrent, ""
+  case "Failed":
+    return HealthFailed, "pod failed"
+  }
+
+  for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+    statuses, _, _ := unstructured.NestedSlice(object.Object, "status", field)
+    for _, entry := range statuses {
+      containerStatus, ok := entry.(map[string]interface{})
+      if !ok {
+        continue
+      }
+      reason, _, _ := unstructured.NestedString(containerStatus, "state", "waiting", "reason")
+      if failedWaitingReasons[reason] {
+        name, _ := containerStatus["name"].(string)
+        return HealthFailed, fmt.Sprintf("container %q is in %s", name, reason)
+      }
+    }
+  }
+
+  return conditionHealth(object, "Ready")
+}
+
+// deploymentHealth is Failed once the progress deadline is exceeded and
+// Current once all replicas are updated and available.
+func deploymentHealth(object *unstructured.Unstructured) (Health, string) {
+  if reason := conditionReason(object, "Progressing"); reason == "ProgressDeadlineExceeded" {
+    return HealthFailed, conditionMessage(object, "Progressing")
+  }
+
+  want := specReplicas(object)
+  if updated := statusInt(object, "updatedReplicas"); updated < want {
+    return HealthInProgress, fmt.Sprintf("%d of %d replicas updated", updated, want)
+  }
+  if replicas := statusInt(object, "replicas"); replicas > want {
+    return HealthInProgress, fmt.Sprintf("%d old replicas pending termination", replicas-want)
+  }
+  if available := statusInt(object, "availableReplicas"); available < want {
+    return HealthInProgress, fmt.Sprintf("%d of %d replicas available", available, want)
+  }
+  return HealthCurrent, ""
+}
+
+// conditionHealth is Current when the condition is true, InProgress
+// otherwise.
+func conditionHealth(object *unstructured.Unstructured, conditionType string) (Health, string) {
+  status, found := condition(object, conditionType)
+  if !found {
+    return HealthInProgress, fmt.Sprintf("condition %s not reported yet", conditionType)
+  }
+  if status != "True" {
+    return HealthInProgress, fmt.Sprintf("condition %s is %s", conditionType, status)
+  }
+  return HealthCurrent, ""
+}
+
+// condition returns the status of a condition of the object.
+func condition(object *unstructured.Unstructured, conditionType string) (string, bool) {
+  entry := conditionEntry(object, conditionType)
+  if entry == nil {
+    return "", false
+  }
+  status, _ := entry["status"].(string)
+  return status, true
+}
+
+// conditionReason returns the reason of a condition of the object.
+func conditionReason(object *unstructured.Unstructured, conditionType string) string {
+  reason, _ := conditionEntry(object, conditionType)["reason"].(string)
+  return reason
+}
+
+// conditionMessage returns the message of a condition of the object.
+func conditionMessage(object *unstructured.Unstructured, conditionType string) string {
+  message, _ := conditionEntry(object, conditionType)["message"].(string)
+  return message
+}
+
+// conditionEntry returns a condition of the object, nil when not reported.
+func conditionEntry(object *unstructured.Unstructured, conditionType string) map[string]interface{} {
+  conditions, _, _ := unstructured.NestedSlice(object.Object, "status", "conditions")
+  for _, entry := range conditions {
+    condition, ok := entry.(map[string]interface{})
+    if ok && condition["type"] == conditionType {
+      return condition
+    }
+  }
+  return nil
+}
+
+// statusInt returns an integer field of the object status.
+func statusInt(object *unstructured.Unstructured, field string) int64 {
+  value, _, _ := unstructured.NestedInt64(object.Object, "status", field)
+  return value
+}
+
+// specReplicas returns the desired replicas of the object, 1 when not set.
+func specReplicas(object *unstructured.Unstructured) int64 {
+  value, found, _ := unstructured.NestedInt64(object.Object, "spec", "replicas")
+  if !found {
+    return 1
+  }
+  return value
+}
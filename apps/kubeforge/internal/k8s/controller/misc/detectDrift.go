@@ -0,0 +1,304 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// DetectDrift compares a live object with the desired one applied
+// by the controller. A field drifted when it is declared by the
+// desired object but either owned by another field manager with a
+// different value (it was changed, e.g. by `kubectl edit`) or
+// missing from the live object (it was removed). Fields co-owned
+// with an equal value (e.g. by the former field manager) did not
+// drift. Only the labels and annotations of the metadata are
+// considered. List items are paired the way the lists are merged:
+// by their merge keys, by value for sets and by position for
+// atomic lists, lists unknown to the schema by name.
+//
+// ############################################################
+
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+
+	yamlMisc "kubeforge/internal/ops/yaml/misc"
+)
+
+// DetectDrift returns the drifted fields of the live object, sorted. The
+// schema, which may be nil, pairs the list items.
+func DetectDrift(
+  desired      *unstructured.Unstructured,
+  live         *unstructured.Unstructured,
+  fieldManager string,
+  schema       yamlMisc.MergeSchema,
+) (
+  []string,
+  error,
+) {
+
+    drifted := map[string]bool{}
+
+    // Declared fields owned by someone else, with another value
+    for _, entry := range live.GetManagedFields() {
+      if entry.Manager == fieldManager || entry.Subresource != "" || entry.FieldsV1 == nil {
+        continue
+      }
+
+      fields := &fieldpath.Set{}
+      if err := fields.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+        return nil, fmt.Errorf("failed to parse managed fields of %q: %v", entry.Manager, err)
+      }
+      fields.Iterate(func(path fieldpath.Path) {
+        if !driftTracked(path) {
+          return
+        }
+        desiredValue, declared := fieldValue(desired.Object, path)
+        if !declared {
+          return
+        }
+        liveValue, _ := fieldValue(live.Object, path)
+        if !valuesEqual(desiredValue, liveValue) {
+          drifted[path.String()] = true
+        }
+      })
+    }
+
+    // Declared fields removed from the live object
+    missingFields(desired.Object, live.Object, "", schema, func(path string) {
+      drifted[path] = true
+    })
+
+    paths := make([]string, 0, len(drifted))
+    for path := range drifted {
+      paths = append(paths, path)
+    }
+    sort.Strings(paths)
+    return paths, nil
+}
+
+// driftTracked reports whether drift of the field is of interest.
+func driftTracked(path fieldpath.Path) bool {
+  if len(path) == 0 || path[0].FieldName == nil {
+    return false
+  }
+  switch *path[0].FieldName {
+  case "status":
+    return false
+  case "metadata":
+    return len(path) > 1 && path[1].FieldName != nil &&
+      (*path[1].FieldName == "labels" || *path[1].FieldName == "annotations")
+  }
+  return true
+}
+
+// fieldValue returns the value the object declares at the path, maps only
+// count through their fields.
+func fieldValue(object interface{}, path fieldpath.Path) (interface{}, bool) {
+  value := object
+  for _, element := range path {
+    switch {
+    case element.FieldName != nil:
+      fields, ok := value.(map[string]interface{})
+      if !ok {
+        return nil, false
+      }
+      if value, ok = fields[*element.FieldName]; !ok {
+        return nil, false
+      }
+
+    case element.Key != nil:
+      items, ok := value.([]interface{})
+      if !ok {
+        return nil, false
+      }
+      value = nil
+      for _, item := range items {
+        fields, ok := item.(map[string]interface{})
+        if !ok {
+          continue
+        }
+        matches := true
+        for _, key := range *element.Key {
+          if fmt.Sprint(fields[key.Name]) != fmt.Sprint(key.Value.Unstructured()) {
+            matches = false
+          }
+        }
+        if matches {
+          value = item
+          break
+        }
+      }
+      if value == nil {
+        return nil, false
+      }
+
+    case element.Value != nil:
+      items, ok := value.([]interface{})
+      if !ok {
+        return nil, false
+      }
+      value = nil
+      for _, item := range items {
+        if fmt.Sprint(item) == fmt.Sprint((*element.Value).Unstructured()) {
+          value = item
+          break
+        }
+      }
+      if value == nil {
+        return nil, false
+      }
+
+    case element.Index != nil:
+      items, ok := value.([]interface{})
+      if !ok || *element.Index >= len(items) {
+        return nil, false
+      }
+      value = items[*element.Index]
+    }
+  }
+
+  _, isMap := value.(map[string]interface{})
+  return value, value != nil && !isMap
+}
+
+// valuesEqual reports whether two values are equal once encoded, numbers
+// decoded as int64 or float64 included.
+func valuesEqual(a, b interface{}) bool {
+  encodedA, errA := json.Marshal(a)
+  encodedB, errB := json.Marshal(b)
+  return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// missingFields reports the fields of desired absent from live.
+func missingFields(desired, live interface{}, path string, schema yamlMisc.MergeSchema, report func(string)) {
+  switch desiredValue := desired.(type) {
+  case map[string]interface{}:
+    liveFields, ok := live.(map[string]interface{})
+    if !ok {
+      report(path)
+      return
+    }
+    for key, value := range desiredValue {
+      if path == "" && (key == "status" || key == "metadata") {
+        continue
+      }
+      fieldPath := path + "." + key
+      liveValue, exists := liveFields[key]
+      if !exists {
+        if !isEmpty(value) {
+          report(fieldPath)
+        }
+        continue
+      }
+      missingFields(value, liveValue, fieldPath, driftFieldSchema(schema, key), report)
+    }
+
+    // Labels and annotations are the only tracked metadata
+    if path == "" {
+      desiredMetadata, _ := desiredValue["metadata"].(map[string]interface{})
+      liveMetadata, _ := liveFields["metadata"].(map[string]interface{})
+      for _, key := range []string{"labels", "annotations"} {
+        if value, exists := desiredMetadata[key]; exists {
+          missingFields(value, liveMetadata[key], ".metadata."+key, nil, report)
+        }
+      }
+    }
+
+  case []interface{}:
+    liveItems, ok := live.([]interface{})
+    if !ok {
+      report(path)
+      return
+    }
+    strategy, mergeKeys := yamlMisc.ListUnknown, []string(nil)
+    var itemSchema yamlMisc.MergeSchema
+    if schema != nil {
+      strategy, mergeKeys = schema.List()
+      itemSchema = schema.Items()
+    }
+    if strategy == yamlMisc.ListUnknown {
+      mergeKeys = []string{"name"}
+    }
+
+    for index, item := range desiredValue {
+      itemPath := fmt.Sprintf("%s[%d]", path, index)
+      var liveItem interface{}
+
+      switch {
+      case (strategy == yamlMisc.ListUnknown || strategy == yamlMisc.ListMerge) && yamlMisc.MergeKey(item, mergeKeys) != "":
+        itemPath = mergeKeyPath(path, item, mergeKeys)
+        key := yamlMisc.MergeKey(item, mergeKeys)
+        for _, candidate := range liveItems {
+          if yamlMisc.MergeKey(candidate, mergeKeys) == key {
+            liveItem = candidate
+            break
+          }
+        }
+
+      case strategy == yamlMisc.ListSet:
+        for _, candidate := range liveItems {
+          if valuesEqual(item, candidate) {
+            liveItem = candidate
+            break
+          }
+        }
+
+      case index < len(liveItems):
+        liveItem = liveItems[index]
+      }
+
+      if liveItem == nil {
+        report(itemPath)
+        continue
+      }
+      missingFields(item, liveItem, itemPath, itemSchema, report)
+    }
+  }
+}
+
+// driftFieldSchema returns the schema of a field, nil when unknown.
+func driftFieldSchema(schema yamlMisc.MergeSchema, key string) yamlMisc.MergeSchema {
+  if schema == nil {
+    return nil
+  }
+  return schema.Field(key)
+}
+
+// mergeKeyPath returns the path of a list item identified by its merge keys,
+// e.g. `.spec.ports[containerPort="80"]`.
+func mergeKeyPath(path string, item interface{}, mergeKeys []string) string {
+  fields, _ := item.(map[string]interface{})
+  keys := []string{}
+  for _, mergeKey := range mergeKeys {
+    var value interface{} = fields
+    for _, field := range strings.Split(mergeKey, ".") {
+      m, _ := value.(map[string]interface{})
+      value = m[field]
+    }
+    if value != nil {
+      keys = append(keys, fmt.Sprintf("%s=%q", mergeKey, fmt.Sprint(value)))
+    }
+  }
+  return fmt.Sprintf("%s[%s]", path, strings.Join(keys, ","))
+}
+
+// isEmpty reports whether a value is null or an empty map or list, which the
+// API server is free to drop.
+func isEmpty(value interface{}) bool {
+  switch typed := value.(type) {
+  case nil:
+    return true
+  case map[string]interface{}:
+    return len(typed) == 0
+  case []interface{}:
+    return len(typed) == 0
+  }
+  return false
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	yamlMisc "kubeforge/internal/ops/yaml/misc"
+)
+
+// driftObject returns an object managed by the given field managers, each
+// owning the fields of its FieldsV1 JSON.
+func driftObject(object map[string]interface{}, managers map[string]string) *unstructured.Unstructured {
+  u := &unstructured.Unstructured{Object: object}
+  entries := []metav1.ManagedFieldsEntry{}
+  for manager, fields := range managers {
+    entries = append(entries, metav1.ManagedFieldsEntry{
+      Manager:    manager,
+      Operation:  metav1.ManagedFieldsOperationUpdate,
+      FieldsType: "FieldsV1",
+      FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+    })
+  }
+  u.SetManagedFields(entries)
+  return u
+}
+
+// podSchema is the merge schema of the built-in Pod.
+var podSchema = BuiltinMergeSchema(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+
+// setSchema merges every list as a set.
+type setSchema struct{}
+
+func (setSchema) Field(string) yamlMisc.MergeSchema         { return setSchema{} }
+func (setSchema) List() (yamlMisc.ListStrategy, []string)   { return yamlMisc.ListSet, nil }
+func (setSchema) Items() yamlMisc.MergeSchema               { return nil }
+
+func TestDetectDrift(t *testing.T) {
+  tests := []struct {
+    name     string
+    desired  map[string]interface{}
+    live     map[string]interface{}
+    managers map[string]string
+    schema   yamlMisc.MergeSchema
+    want     []string
+  }{
+    {
+      name:     "unchanged",
+      desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+      live:     map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+      managers: map[string]string{"kubeforge": `{"f:data":{"f:a":{}}}`},
+      want:     []string{},
+    },
+    {
+      name:     "changed by another manager",
+      desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+      live:     map[string]interface{}{"data": map[string]interface{}{"a": "2"}},
+      managers: map[string]string{"kubeforge": `{}`, "kubectl-edit": `{"f:data":{"f:a":{}}}`},
+      want:     []string{".data.a"},
+    },
+    {
+      name:     "co-owned with an equal value",
+      desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+      live:     map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+      managers: map[string]string{"kubeforge": `{"f:data":{"f:a":{}}}`, "before-first-apply": `{"f:data":{"f:a":{}}}`},
+      want:     []string{},
+    },
+    {
+      name:     "numbers compare by value",
+      desired:  map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}},
+      live:     map[string]interface{}{"spec": map[string]interface{}{"replicas": float64(2)}},
+      managers: map[string]string{"other": `{"f:spec":{"f:replicas":{}}}`},
+      want:     []string{},
+    },
+    {
+      name:     "undeclared fields of others are ignored",
+      desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+      live:     map[string]interface{}{"data": map[string]interface{}{"a": "1", "b": "2"}},
+      managers: map[string]string{"other": `{"f:data":{"f:b":{}}}`},
+      want:     []string{},
+    },
+    {
+      name:     "removed field",
+      desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1", "b": "2"}},
+      live:     map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+      want:     []string{".data.b"},
+    },
+    {
+      name:     "empty values may be dropped",
+      desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1", "b": map[string]interface{}{}}},
+      live:     map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+      want:     []string{},
+    },
+    {
+      name: "status and untracked metadata are ignored",
+      desired: map[string]interface{}{
+        "metadata": map[string]interface{}{"name": "a", "labels": map[string]interface{}{"app": "web"}},
+        "status":   map[string]interface{}{"phase": "Running"},
+      },
+      live: map[string]interface{}{
+        "metadata": map[string]interface{}{"name": "a", "labels": map[string]interface{}{"app": "web"}},
+      },
+      managers: map[string]string{"other": `{"f:metadata":{"f:name":{}},"f:status":{"f:phase":{}}}`},
+      want:     []string{},
+    },
+    {
+      name: "removed label",
+      desired: map[string]interface{}{
+        "metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web", "tier": "front"}},
+      },
+      live: map[string]interface{}{
+        "metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}},
+      },
+      want: []string{".metadata.labels.tier"},
+    },
+    {
+      name: "named list items are matched by name",
+      desired: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "image": "a"},
+        map[string]interface{}{"name": "b", "image": "b"},
+      }}},
+      live: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "b", "image": "b"},
+        map[string]interface{}{"name": "a", "image": "a"},
+      }}},
+      want: []string{},
+    },
+    {
+      name: "removed named list item",
+      desired: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "image": "a"},
+        map[string]interface{}{"name": "b", "image": "b"},
+      }}},
+      live: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "image": "a"},
+      }}},
+      want: []string{`.spec.containers[name="b"]`},
+    },
+    {
+      name: "changed named list item",
+      desired: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "image": "a:1"},
+      }}},
+      live: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "image": "a:2"},
+      }}},
+      managers: map[string]string{"other": `{"f:spec":{"f:containers":{"k:{\"name\":\"a\"}":{"f:image":{}}}}}`},
+      want:     []string{`.spec.containers[name="a"].image`},
+    },
+    {
+      name:    "removed list value",
+      desired: map[string]interface{}{"spec": map[string]interface{}{"args": []interface{}{"a", "b"}}},
+      live:    map[string]interface{}{"spec": map[string]interface{}{"args": []interface{}{"a"}}},
+      want:    []string{".spec.args[1]"},
+    },
+    {
+      name: "merge keyed list items are matched by their merge keys",
+      desired: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "ports": []interface{}{
+          map[string]interface{}{"containerPort": int64(80)},
+          map[string]interface{}{"containerPort": int64(443)},
+        }},
+      }}},
+      live: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "ports": []interface{}{
+          map[string]interface{}{"containerPort": int64(443)},
+          map[string]interface{}{"containerPort": int64(80)},
+        }},
+      }}},
+      schema: podSchema,
+      want:   []string{},
+    },
+    {
+      name: "removed merge keyed list item",
+      desired: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "ports": []interface{}{
+          map[string]interface{}{"containerPort": int64(80)},
+          map[string]interface{}{"containerPort": int64(443)},
+        }},
+      }}},
+      live: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "ports": []interface{}{
+          map[string]interface{}{"containerPort": int64(80)},
+        }},
+      }}},
+      schema: podSchema,
+      want:   []string{`.spec.containers[name="a"].ports[containerPort="443"]`},
+    },
+    {
+      name: "set values are matched by value",
+      desired: map[string]interface{}{"metadata": map[string]interface{}{}, "finalizers": []interface{}{"a", "b"}},
+      live:    map[string]interface{}{"metadata": map[string]interface{}{}, "finalizers": []interface{}{"b", "a"}},
+      schema:  setSchema{},
+      want:    []string{},
+    },
+    {
+      name: "atomic lists are matched by position",
+      desired: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "args": []interface{}{"x", "y"}},
+      }}},
+      live: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+        map[string]interface{}{"name": "a", "args": []interface{}{"x"}},
+      }}},
+      schema: podSchema,
+      want:   []string{`.spec.containers[name="a"].args[1]`},
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      desired := &unstructured.Unstructured{Object: test.desired}
+      live := driftObject(test.live, test.managers)
+      got, err := DetectDrift(desired, live, "kubeforge", test.schema)
+      if err != nil {
+        t.Fatalf("DetectDrift() error = %v", err)
+      }
+      if !reflect.DeepEqual(got, test.want) {
+        t.Errorf("DetectDrift() = %q, want %q", got, test.want)
+      }
+    })
+  }
+}
@@ -0,0 +1,29 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Invalid errors of the API server about a field that can not be
+// changed in place (e.g. the selector of a Deployment), applying
+// them again never succeeds so the resource is recreated instead.
+//
+// ############################################################
+
+package controller
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// IsImmutableFieldError reports whether the API server rejected a change
+// because it modifies a field that can not be updated in place.
+func IsImmutableFieldError(err error) bool {
+	if !errors.IsInvalid(err) {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "field is immutable") ||
+		strings.Contains(message, "may not change fields")
+}
@@ -0,0 +1,50 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// A namespaced Overlay can not be the owner of a cluster-scoped
+// object, such children carry labels and annotations pointing back
+// to their Overlay instead of a controller OwnerReference.
+//
+// ############################################################
+
+package controller
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+  // OverlayUIDLabel holds the UID of the Overlay owning a cluster-scoped
+  // object
+  OverlayUIDLabel = "kubeforge.sh/overlay-uid"
+
+  // OverlayAnnotation holds the `namespace/name` of the Overlay owning a
+  // cluster-scoped object
+  OverlayAnnotation = "kubeforge.sh/overlay"
+)
+
+// IsOwnedBy reports whether the object is controlled by the owner, through
+// its OwnerReference or its ownership label.
+func IsOwnedBy(object metav1.Object, owner metav1.Object) bool {
+  if metav1.IsControlledBy(object, owner) {
+    return true
+  }
+  return owner.GetUID() != "" && object.GetLabels()[OverlayUIDLabel] == string(owner.GetUID())
+}
+
+// GetOverlayOf returns the Overlay recorded on a cluster-scoped object and
+// whether there is one.
+func GetOverlayOf(object metav1.Object) (types.NamespacedName, types.UID, bool) {
+  uid := object.GetLabels()[OverlayUIDLabel]
+  namespace, name, found := strings.Cut(object.GetAnnotations()[OverlayAnnotation], "/")
+  if uid == "" || !found {
+    return types.NamespacedName{}, "", false
+  }
+  return types.NamespacedName{Namespace: namespace, Name: name}, types.UID(uid), true
+}
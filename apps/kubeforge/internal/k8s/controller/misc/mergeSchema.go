@@ -0,0 +1,313 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Merge schemas tell the YAML merge how to merge the lists of a
+// kind, the way `kubectl apply` does. They are read from the
+// OpenAPI v3 document of the cluster (`x-kubernetes-patch-strategy`,
+// `x-kubernetes-patch-merge-key`, `x-kubernetes-list-type` and
+// `x-kubernetes-list-map-keys`), or from the `patchStrategy` and
+// `patchMergeKey` tags of the built-in types compiled into the
+// binary when there is no cluster or it does not know the kind.
+//
+// ############################################################
+
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/openapi3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+
+	yamlMisc "kubeforge/internal/ops/yaml/misc"
+)
+
+// openAPICacheTTL is the time an OpenAPI document of a group version, or
+// its absence, is used before it is fetched again, so changed CRD schemas
+// are picked up.
+const openAPICacheTTL = 5 * time.Minute
+
+// openAPIDocument holds the schemas of a group version.
+type openAPIDocument struct {
+  components map[string]*spec.Schema
+  kinds      map[schema.GroupVersionKind]*spec.Schema
+}
+
+// openAPIEntry caches the document of a group version, nil when the cluster
+// serves none. Its mutex is held while fetching, so concurrent lookups of
+// the group version wait for the same download.
+type openAPIEntry struct {
+  mutex    sync.Mutex
+  fetched  time.Time
+  document *openAPIDocument
+}
+
+// MergeSchemas looks up the merge schemas of resource types.
+type MergeSchemas struct {
+  mapper    *ResourceMapper
+  discovery discovery.DiscoveryInterface
+
+  mutex     sync.Mutex
+  documents map[schema.GroupVersion]*openAPIEntry
+}
+
+// NewMergeSchemas returns the merge schemas of the cluster, the discovery
+// client may be nil to only rely on the built-in types.
+func NewMergeSchemas(mapper *ResourceMapper, discoveryClient discovery.DiscoveryInterface) *MergeSchemas {
+  return &MergeSchemas{
+    mapper:    mapper,
+    discovery: discoveryClient,
+    documents: map[schema.GroupVersion]*openAPIEntry{},
+  }
+}
+
+// For returns the merge schema of a resource type, nil when it is unknown.
+func (schemas *MergeSchemas) For(resourceType string) yamlMisc.MergeSchema {
+  mapping, err := schemas.mapper.MappingFor(resourceType)
+  if err != nil {
+    return nil
+  }
+  return schemas.ForKind(mapping.GroupVersionKind)
+}
+
+// ForKind returns the merge schema of a kind, nil when it is unknown.
+func (schemas *MergeSchemas) ForKind(kind schema.GroupVersionKind) yamlMisc.MergeSchema {
+  if document := schemas.document(kind.GroupVersion()); document != nil {
+    if kindSchema, exists := document.kinds[kind]; exists {
+      return openAPIMergeSchema{components: document.components, schema: kindSchema}
+    }
+  }
+  return BuiltinMergeSchema(kind)
+}
+
+// document returns the OpenAPI document of a group version, nil when the
+// cluster does not serve one.
+func (schemas *MergeSchemas) document(groupVersion schema.GroupVersion) *openAPIDocument {
+  if schemas.discovery == nil {
+    return nil
+  }
+
+  // The lock of the cache is only held to look up the entry
+  schemas.mutex.Lock()
+  entry, exists := schemas.documents[groupVersion]
+  if !exists {
+    entry = &openAPIEntry{}
+    schemas.documents[groupVersion] = entry
+  }
+  schemas.mutex.Unlock()
+
+  entry.mutex.Lock()
+  defer entry.mutex.Unlock()
+
+  if !entry.fetched.IsZero() && time.Since(entry.fetched) < openAPICacheTTL {
+    return entry.document
+  }
+  entry.document = schemas.fetchDocument(groupVersion)
+  entry.fetched = time.Now()
+  return entry.document
+}
+
+// fetchDocument downloads the OpenAPI document of a group version, nil when
+// the cluster does not serve one.
+func (schemas *MergeSchemas) fetchDocument(groupVersion schema.GroupVersion) *openAPIDocument {
+  openAPI, err := openapi3.NewRoot(schemas.discovery.OpenAPIV3()).GVSpec(groupVersion)
+  if err != nil || openAPI.Components == nil {
+    return nil
+  }
+
+  document := &openAPIDocument{
+    components: openAPI.Components.Schemas,
+    kinds:      map[schema.GroupVersionKind]*spec.Schema{},
+  }
+  for _, componentSchema := range openAPI.Components.Schemas {
+    var kinds []schema.GroupVersionKind
+    if err := componentSchema.Extensions.GetObject("x-kubernetes-group-version-kind", &kinds); err != nil {
+      continue
+    }
+    for _, kind := range kinds {
+      document.kinds[kind] = componentSchema
+    }
+  }
+
+  return document
+}
+
+// openAPIMergeSchema reads the merge schema out of an OpenAPI v3 schema.
+type openAPIMergeSchema struct {
+  components map[string]*spec.Schema
+  schema     *spec.Schema
+}
+
+// resolved follows the references of the schema.
+func (s openAPIMergeSchema) resolved() *spec.Schema {
+  current := s.schema
+  for current != nil {
+    if len(current.AllOf) > 0 {
+      current = &current.AllOf[0]
+      continue
+    }
+    reference := current.Ref.String()
+    if reference == "" {
+      return current
+    }
+    current = s.components[strings.TrimPrefix(reference, "#/components/schemas/")]
+  }
+  return nil
+}
+
+// with returns the merge schema of a sub schema, nil when there is none.
+func (s openAPIMergeSchema) with(subSchema *spec.Schema) yamlMisc.MergeSchema {
+  if subSchema == nil {
+    return nil
+  }
+  return openAPIMergeSchema{components: s.components, schema: subSchema}
+}
+
+func (s openAPIMergeSchema) Field(key string) yamlMisc.MergeSchema {
+  current := s.resolved()
+  if current == nil {
+    return nil
+  }
+  if property, exists := current.Properties[key]; exists {
+    return s.with(&property)
+  }
+  if current.AdditionalProperties != nil {
+    return s.with(current.AdditionalProperties.Schema)
+  }
+  return nil
+}
+
+func (s openAPIMergeSchema) List() (yamlMisc.ListStrategy, []string) {
+
+  // The extensions sit on the field, or on the schema it refers to
+  extension := func(key string) (string, bool) {
+    if value, found := s.schema.Extensions.GetString(key); found {
+      return value, true
+    }
+    if current := s.resolved(); current != nil {
+      return current.Extensions.GetString(key)
+    }
+    return "", false
+  }
+
+  // Patch strategies are what `kubectl apply` merges with
+  patchStrategy, _ := extension("x-kubernetes-patch-strategy")
+  if strings.Contains(patchStrategy, "merge") {
+    if mergeKey, found := extension("x-kubernetes-patch-merge-key"); found {
+      return yamlMisc.ListMerge, []string{mergeKey}
+    }
+    return yamlMisc.ListSet, nil
+  }
+
+  listType, _ := extension("x-kubernetes-list-type")
+  switch listType {
+  case "map":
+    mapKeys, found := s.schema.Extensions.GetStringSlice("x-kubernetes-list-map-keys")
+    if !found {
+      if current := s.resolved(); current != nil {
+        mapKeys, found = current.Extensions.GetStringSlice("x-kubernetes-list-map-keys")
+      }
+    }
+    if found && len(mapKeys) > 0 {
+      return yamlMisc.ListMerge, mapKeys
+    }
+  case "set":
+    return yamlMisc.ListSet, nil
+  }
+  return yamlMisc.ListReplace, nil
+}
+
+func (s openAPIMergeSchema) Items() yamlMisc.MergeSchema {
+  current := s.resolved()
+  if current == nil || current.Items == nil {
+    return nil
+  }
+  return s.with(current.Items.Schema)
+}
+
+// BuiltinMergeSchema returns the merge schema of a built-in kind out of the
+// tags of its Go type, nil for other kinds.
+func BuiltinMergeSchema(kind schema.GroupVersionKind) yamlMisc.MergeSchema {
+  object, err := scheme.Scheme.New(kind)
+  if err != nil {
+    return nil
+  }
+  return structMergeSchema{kind: reflect.TypeOf(object)}
+}
+
+// structMergeSchema reads the merge schema out of a Go type and the tag of
+// the field holding it.
+type structMergeSchema struct {
+  kind reflect.Type
+  tag  reflect.StructTag
+}
+
+// elem strips the pointers of a type.
+func elem(kind reflect.Type) reflect.Type {
+  for kind.Kind() == reflect.Pointer {
+    kind = kind.Elem()
+  }
+  return kind
+}
+
+// structField finds the field serialized under the key, inline fields
+// included.
+func structField(kind reflect.Type, key string) (reflect.StructField, bool) {
+  for index := 0; index < kind.NumField(); index++ {
+    field := kind.Field(index)
+    name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+    if field.Anonymous && strings.Contains(options, "inline") {
+      if inlineField, found := structField(elem(field.Type), key); found {
+        return inlineField, true
+      }
+      continue
+    }
+    if name == key {
+      return field, true
+    }
+  }
+  return reflect.StructField{}, false
+}
+
+func (s structMergeSchema) Field(key string) yamlMisc.MergeSchema {
+  kind := elem(s.kind)
+  switch kind.Kind() {
+  case reflect.Struct:
+    if field, found := structField(kind, key); found {
+      return structMergeSchema{kind: field.Type, tag: field.Tag}
+    }
+  case reflect.Map:
+    return structMergeSchema{kind: kind.Elem()}
+  }
+  return nil
+}
+
+func (s structMergeSchema) List() (yamlMisc.ListStrategy, []string) {
+  kind := elem(s.kind)
+  if kind.Kind() != reflect.Slice {
+    return yamlMisc.ListUnknown, nil
+  }
+  if strings.Contains(s.tag.Get("patchStrategy"), "merge") {
+    if mergeKey := s.tag.Get("patchMergeKey"); mergeKey != "" {
+      return yamlMisc.ListMerge, []string{mergeKey}
+    }
+    return yamlMisc.ListSet, nil
+  }
+  return yamlMisc.ListReplace, nil
+}
+
+func (s structMergeSchema) Items() yamlMisc.MergeSchema {
+  kind := elem(s.kind)
+  if kind.Kind() != reflect.Slice {
+    return nil
+  }
+  return structMergeSchema{kind: kind.Elem()}
+}
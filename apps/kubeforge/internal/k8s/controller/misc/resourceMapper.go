@@ -0,0 +1,122 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// ResourceMapper resolves resource types through a RESTMapper backed
+// by an in-memory discovery cache, so discovery is only queried once
+// instead of on every lookup. Kinds resolve to their preferred
+// version. A miss invalidates the cache and retries once, so CRDs
+// installed after the start are found; invalidations are throttled
+// to keep unknown kinds from hammering the API server.
+//
+// ############################################################
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+)
+
+// mapperResetInterval is the minimum time between two cache invalidations.
+const mapperResetInterval = 10 * time.Second
+
+type ResourceMapper struct {
+  mapper    *restmapper.DeferredDiscoveryRESTMapper
+  mutex     sync.Mutex
+  lastReset time.Time
+}
+
+func NewResourceMapper(discoveryClient discovery.DiscoveryInterface) *ResourceMapper {
+  return &ResourceMapper{
+    mapper: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+  }
+}
+
+// MappingFor resolves a resource type key, a kind (e.g. "Pod") or a resource
+// name (e.g. "configmaps") optionally qualified by group and version, into
+// its mapping. Without version the preferred one is used.
+func (resourceMapper *ResourceMapper) MappingFor(resourceType string) (*meta.RESTMapping, error) {
+
+  parsed, err := ParseResourceType(resourceType)
+  if err != nil {
+    return nil, err
+  }
+  resource := schema.GroupVersionResource{
+    Group:    parsed.Group,
+    Version:  parsed.Version,
+    Resource: strings.ToLower(parsed.Name),
+  }
+
+  var mapping *meta.RESTMapping
+  err = resourceMapper.retryOnMiss(func() error {
+    candidates, err := resourceMapper.mapper.KindsFor(resource)
+    if err != nil {
+      return err
+    }
+    kind, err := SelectKind(resourceType, parsed, candidates)
+    if err != nil {
+      return err
+    }
+    mapping, err = resourceMapper.mapper.RESTMapping(kind.GroupKind(), kind.Version)
+    return err
+  })
+  if err != nil {
+    return nil, fmt.Errorf("failed to resolve resource type %q: %w", resourceType, err)
+  }
+  return mapping, nil
+}
+
+// MappingForKind resolves a fully qualified kind, falling back to the
+// preferred version of its group when the version is no longer served.
+func (resourceMapper *ResourceMapper) MappingForKind(kind schema.GroupVersionKind) (*meta.RESTMapping, error) {
+
+  var mapping *meta.RESTMapping
+  err := resourceMapper.retryOnMiss(func() (err error) {
+    mapping, err = resourceMapper.mapper.RESTMapping(kind.GroupKind(), kind.Version)
+    if meta.IsNoMatchError(err) {
+      mapping, err = resourceMapper.mapper.RESTMapping(kind.GroupKind())
+    }
+    return err
+  })
+  if err != nil {
+    return nil, fmt.Errorf("failed to resolve kind %q: %w", kind, err)
+  }
+  return mapping, nil
+}
+
+// retryOnMiss runs the lookup again on a fresh cache when it did not match.
+func (resourceMapper *ResourceMapper) retryOnMiss(lookup func() error) error {
+  err := lookup()
+  if !meta.IsNoMatchError(err) || !resourceMapper.allowReset() {
+    return err
+  }
+  resourceMapper.mapper.Reset()
+  return lookup()
+}
+
+// allowReset reports whether the cache may be invalidated now.
+func (resourceMapper *ResourceMapper) allowReset() bool {
+  resourceMapper.mutex.Lock()
+  defer resourceMapper.mutex.Unlock()
+
+  if time.Since(resourceMapper.lastReset) < mapperResetInterval {
+    return false
+  }
+  resourceMapper.lastReset = time.Now()
+  return true
+}
+
+// IsNamespaced reports whether the mapping is of a namespaced resource.
+func IsNamespaced(mapping *meta.RESTMapping) bool {
+  return mapping.Scope.Name() == meta.RESTScopeNameNamespace
+}
@@ -0,0 +1,145 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Resource type keys of the overlay data name a kind or a resource,
+// optionally qualified by its group and version:
+//
+//   Deployment, deployments     bare, must be unambiguous
+//   apps/v1/Deployment, v1/Pod  group/version/Kind, version/Kind
+//   Deployment.apps             Kind.group
+//   Deployment.v1.apps          Kind.version.group
+//
+// ############################################################
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// versionPattern matches Kubernetes API versions (e.g. v1, v2beta1).
+var versionPattern = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// ResourceType is a parsed resource type key.
+type ResourceType struct {
+  Group          string
+  Version        string
+  Name           string // kind or resource name
+  GroupQualified bool   // group given explicitly, "" being the core group
+}
+
+// ParseResourceType parses a resource type key.
+func ParseResourceType(resourceType string) (ResourceType, error) {
+
+  if strings.Contains(resourceType, "/") {
+    parts := strings.Split(resourceType, "/")
+    switch {
+    case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+      return ResourceType{Version: parts[0], Name: parts[1], GroupQualified: true}, nil
+    case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+      return ResourceType{Group: parts[0], Version: parts[1], Name: parts[2], GroupQualified: true}, nil
+    }
+    return ResourceType{}, fmt.Errorf("invalid resource type %q, expected group/version/Kind", resourceType)
+  }
+
+  name, rest, qualified := strings.Cut(resourceType, ".")
+  if !qualified {
+    return ResourceType{Name: resourceType}, nil
+  }
+  if name == "" || rest == "" {
+    return ResourceType{}, fmt.Errorf("invalid resource type %q, expected Kind.group", resourceType)
+  }
+
+  // Kind.version.group, as long as the first part is a version
+  if version, group, found := strings.Cut(rest, "."); found && versionPattern.MatchString(version) {
+    return ResourceType{Group: group, Version: version, Name: name, GroupQualified: true}, nil
+  }
+  return ResourceType{Group: rest, Name: name, GroupQualified: true}, nil
+}
+
+// QualifiedKey returns the unambiguous resource type key of a kind.
+func QualifiedKey(kind schema.GroupVersionKind) string {
+  if kind.Group == "" {
+    return kind.Version + "/" + kind.Kind
+  }
+  return kind.Group + "/" + kind.Version + "/" + kind.Kind
+}
+
+// SelectKind picks the kind of a resource type out of the candidates, given
+// in priority order. Exact kind matches win over resource name matches, a
+// bare kind matching kinds of several groups is ambiguous. A bare resource
+// name (e.g. "pods", also served by metrics.k8s.io) resolves to the core
+// group, or else to the preferred one.
+func SelectKind(
+  resourceType string,
+  parsed       ResourceType,
+  candidates   []schema.GroupVersionKind,
+) (
+  schema.GroupVersionKind,
+  error,
+) {
+
+    matches := []schema.GroupVersionKind{}
+    for _, candidate := range candidates {
+      if parsed.GroupQualified && candidate.Group != parsed.Group {
+        continue
+      }
+      if parsed.Version != "" && candidate.Version != parsed.Version {
+        continue
+      }
+      matches = append(matches, candidate)
+    }
+
+    exactMatches := []schema.GroupVersionKind{}
+    for _, match := range matches {
+      if strings.EqualFold(match.Kind, parsed.Name) {
+        exactMatches = append(exactMatches, match)
+      }
+    }
+    if len(exactMatches) > 0 {
+      matches = exactMatches
+    }
+
+    if len(matches) == 0 {
+      return schema.GroupVersionKind{}, &meta.NoResourceMatchError{
+        PartialResource: schema.GroupVersionResource{Group: parsed.Group, Version: parsed.Version, Resource: parsed.Name},
+      }
+    }
+
+    // Resource name matches, the first match being the preferred group
+    if len(exactMatches) == 0 {
+      for _, match := range matches {
+        if match.Group == "" {
+          return match, nil
+        }
+      }
+      return matches[0], nil
+    }
+
+    // Versions of the same group and kind are not ambiguous, the first one
+    // is the preferred one
+    keys := []string{}
+    seen := map[schema.GroupKind]bool{}
+    for _, match := range matches {
+      if !seen[match.GroupKind()] {
+        seen[match.GroupKind()] = true
+        keys = append(keys, QualifiedKey(match))
+      }
+    }
+    if len(keys) > 1 {
+      return schema.GroupVersionKind{}, fmt.Errorf(
+        "resource type %q is ambiguous, use one of: %s",
+        resourceType,
+        strings.Join(keys, ", "),
+      )
+    }
+
+    return matches[0], nil
+}
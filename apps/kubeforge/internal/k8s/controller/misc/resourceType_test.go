@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseResourceType(t *testing.T) {
+  tests := []struct {
+    name         string
+    resourceType string
+    want         ResourceType
+    wantErr      string
+  }{
+    {name: "kind", resourceType: "Deployment", want: ResourceType{Name: "Deployment"}},
+    {name: "resource name", resourceType: "deployments", want: ResourceType{Name: "deployments"}},
+    {name: "version/Kind", resourceType: "v1/Pod", want: ResourceType{Version: "v1", Name: "Pod", GroupQualified: true}},
+    {name: "group/version/Kind", resourceType: "apps/v1/Deployment", want: ResourceType{Group: "apps", Version: "v1", Name: "Deployment", GroupQualified: true}},
+    {name: "Kind.group", resourceType: "Deployment.apps", want: ResourceType{Group: "apps", Name: "Deployment", GroupQualified: true}},
+    {name: "Kind.dotted group", resourceType: "Certificate.cert-manager.io", want: ResourceType{Group: "cert-manager.io", Name: "Certificate", GroupQualified: true}},
+    {name: "Kind.version.group", resourceType: "Deployment.v1.apps", want: ResourceType{Group: "apps", Version: "v1", Name: "Deployment", GroupQualified: true}},
+    {name: "Kind.prerelease version.group", resourceType: "HorizontalPodAutoscaler.v2beta1.autoscaling", want: ResourceType{Group: "autoscaling", Version: "v2beta1", Name: "HorizontalPodAutoscaler", GroupQualified: true}},
+    {name: "empty group", resourceType: "/v1/Pod", wantErr: "expected group/version/Kind"},
+    {name: "too many parts", resourceType: "a/b/c/d", wantErr: "expected group/version/Kind"},
+    {name: "missing kind", resourceType: "v1/", wantErr: "expected group/version/Kind"},
+    {name: "missing group", resourceType: "Deployment.", wantErr: "expected Kind.group"},
+    {name: "missing name", resourceType: ".apps", wantErr: "expected Kind.group"},
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      got, err := ParseResourceType(test.resourceType)
+      if test.wantErr != "" {
+        if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+          t.Fatalf("ParseResourceType() error = %v, want %q", err, test.wantErr)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("ParseResourceType() error = %v", err)
+      }
+      if got != test.want {
+        t.Errorf("ParseResourceType() = %+v, want %+v", got, test.want)
+      }
+    })
+  }
+}
+
+func TestSelectKind(t *testing.T) {
+  var (
+    pod           = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+    podMetrics    = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"}
+    deployment    = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+    deploymentOld = schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"}
+    event         = schema.GroupVersionKind{Version: "v1", Kind: "Event"}
+    eventEvents   = schema.GroupVersionKind{Group: "events.k8s.io", Version: "v1", Kind: "Event"}
+    certificate   = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+    certificateX  = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Certificate"}
+  )
+
+  tests := []struct {
+    name         string
+    resourceType string
+    candidates   []schema.GroupVersionKind
+    want         schema.GroupVersionKind
+    wantErr      string
+  }{
+    {name: "kind", resourceType: "Deployment", candidates: []schema.GroupVersionKind{deployment}, want: deployment},
+    {name: "kind is case-insensitive", resourceType: "deployment", candidates: []schema.GroupVersionKind{deployment}, want: deployment},
+    {name: "preferred version", resourceType: "Deployment", candidates: []schema.GroupVersionKind{deployment, deploymentOld}, want: deployment},
+    {name: "explicit version", resourceType: "apps/v1beta1/Deployment", candidates: []schema.GroupVersionKind{deployment, deploymentOld}, want: deploymentOld},
+    {name: "bare resource name resolves to the core group", resourceType: "pods", candidates: []schema.GroupVersionKind{podMetrics, pod}, want: pod},
+    {name: "bare resource name resolves to the preferred group", resourceType: "certificates", candidates: []schema.GroupVersionKind{certificate, certificateX}, want: certificate},
+    {name: "qualified resource name", resourceType: "pods.metrics.k8s.io", candidates: []schema.GroupVersionKind{podMetrics, pod}, want: podMetrics},
+    {name: "kind of several groups is ambiguous", resourceType: "Event", candidates: []schema.GroupVersionKind{event, eventEvents}, wantErr: `resource type "Event" is ambiguous, use one of: v1/Event, events.k8s.io/v1/Event`},
+    {name: "qualified kind of several groups", resourceType: "Event.events.k8s.io", candidates: []schema.GroupVersionKind{event, eventEvents}, want: eventEvents},
+    {name: "core group kind of several groups", resourceType: "v1/Event", candidates: []schema.GroupVersionKind{event, eventEvents}, want: event},
+    {name: "kind of another group", resourceType: "Deployment.extensions", candidates: []schema.GroupVersionKind{deployment}, wantErr: "no matches for"},
+    {name: "no candidates", resourceType: "Unknown", wantErr: "no matches for"},
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      parsed, err := ParseResourceType(test.resourceType)
+      if err != nil {
+        t.Fatalf("ParseResourceType() error = %v", err)
+      }
+      got, err := SelectKind(test.resourceType, parsed, test.candidates)
+      if test.wantErr != "" {
+        if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+          t.Fatalf("SelectKind() error = %v, want %q", err, test.wantErr)
+        }
+        if strings.HasPrefix(test.wantErr, "no matches") && !meta.IsNoMatchError(err) {
+          t.Errorf("SelectKind() error = %v, want a NoResourceMatchError", err)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("SelectKind() error = %v", err)
+      }
+      if !reflect.DeepEqual(got, test.want) {
+        t.Errorf("SelectKind() = %v, want %v", got, test.want)
+      }
+    })
+  }
+}
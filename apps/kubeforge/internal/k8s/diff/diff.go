@@ -0,0 +1,270 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Package diff compares rendered resources with the live ones and
+// tells what the controller would do with each of them: create,
+// update, recreate (an immutable field changed) or prune. Updates
+// are previewed with a server-side dry-run apply using the same
+// field manager as the controller, so the diff includes the fields
+// defaulted by the API server. Drift reverted by the controller is
+// shown as an update.
+//
+// ############################################################
+
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+	"kubeforge/internal/k8s/render"
+
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
+)
+
+// Action is what the controller would do with a resource.
+type Action string
+
+const (
+  ActionUnchanged Action = "unchanged"
+  ActionCreate    Action = "create"
+  ActionUpdate    Action = "update"
+  ActionRecreate  Action = "recreate"
+  ActionPrune     Action = "prune"
+)
+
+// Result is the diff of a single resource.
+type Result struct {
+  Kind      schema.GroupVersionKind
+  Namespace string
+  Name      string
+  Action    Action
+  Diff      string
+}
+
+// KindResolver resolves the mapping of a fully qualified kind.
+type KindResolver func(kind schema.GroupVersionKind) (*meta.RESTMapping, error)
+
+// Resources diffs each rendered resource against its live counterpart.
+func Resources(
+  ctx          context.Context,
+  dynClient    dynamic.Interface,
+  fieldManager string,
+  driftPolicy  crdv1.DriftPolicy,
+  mergeSchemas *controllerMisc.MergeSchemas,
+  resources    []render.Resource,
+) (
+  []Result,
+  error,
+) {
+
+    results := []Result{}
+    for _, resource := range resources {
+      result, err := diffResource(ctx, dynClient, fieldManager, driftPolicy, mergeSchemas, resource)
+      if err != nil {
+        return nil, err
+      }
+      results = append(results, result)
+    }
+    return results, nil
+}
+
+// diffResource diffs a single rendered resource, following the same decisions
+// as the controller does when applying it.
+func diffResource(
+  ctx          context.Context,
+  dynClient    dynamic.Interface,
+  fieldManager string,
+  driftPolicy  crdv1.DriftPolicy,
+  mergeSchemas *controllerMisc.MergeSchemas,
+  resource     render.Resource,
+) (
+  Result,
+  error,
+) {
+
+    desired := resource.Object
+    result := Result{
+      Kind:      desired.GroupVersionKind(),
+      Namespace: desired.GetNamespace(),
+      Name:      desired.GetName(),
+    }
+
+    resourceClient := dynClient.Resource(resource.Schema).Namespace(desired.GetNamespace())
+    live, err := resourceClient.Get(ctx, desired.GetName(), metav1.GetOptions{})
+    if errors.IsNotFound(err) {
+      result.Action = ActionCreate
+      result.Diff, err = unifiedDiff(nil, desired)
+      return result, err
+    }
+    if err != nil {
+      return result, err
+    }
+
+    desiredAnnotation := desired.GetAnnotations()["kubeforge.sh/last-applied-configuration"]
+    liveAnnotation := live.GetAnnotations()["kubeforge.sh/last-applied-configuration"]
+    if desiredAnnotation == liveAnnotation {
+      // Drift is only reverted by the enforce policy
+      if driftPolicy != "" && driftPolicy != crdv1.DriftPolicyEnforce {
+        result.Action = ActionUnchanged
+        return result, nil
+      }
+      driftedFields, err := controllerMisc.DetectDrift(desired, live, fieldManager, mergeSchemas.ForKind(desired.GroupVersionKind()))
+      if err != nil {
+        return result, err
+      }
+      if len(driftedFields) == 0 {
+        result.Action = ActionUnchanged
+        return result, nil
+      }
+    }
+
+    applyData, err := json.Marshal(desired.Object)
+    if err != nil {
+      return result, err
+    }
+
+    forceApply := true
+    applied, err := resourceClient.Patch(
+      ctx,
+      desired.GetName(),
+      types.ApplyPatchType,
+      applyData,
+      metav1.PatchOptions{FieldManager: fieldManager, Force: &forceApply, DryRun: []string{metav1.DryRunAll}},
+    )
+    if controllerMisc.IsImmutableFieldError(err) {
+      result.Action = ActionRecreate
+      result.Diff, err = unifiedDiff(live, desired)
+      return result, err
+    }
+    if err != nil {
+      return result, err
+    }
+
+    result.Action = ActionUpdate
+    result.Diff, err = unifiedDiff(live, applied)
+    return result, err
+}
+
+// Pruned lists the resources of the inventory that are no longer rendered and
+// would be deleted by the controller.
+func Pruned(
+  ctx        context.Context,
+  dynClient  dynamic.Interface,
+  crdOverlay *crdv1.Overlay,
+  resources  []render.Resource,
+  resolver   KindResolver,
+) (
+  []Result,
+  error,
+) {
+
+    if crdOverlay.Spec.Prune != nil && !*crdOverlay.Spec.Prune {
+      return nil, nil
+    }
+
+    rendered := map[string]bool{}
+    for _, resource := range resources {
+      kind := resource.Object.GroupVersionKind()
+      rendered[pruneKey(kind.Group, kind.Kind, resource.Object.GetNamespace(), resource.Object.GetName())] = true
+    }
+
+    results := []Result{}
+    for _, entry := range crdOverlay.Status.Resources {
+      if rendered[pruneKey(entry.Group, entry.Kind, entry.Namespace, entry.Name)] {
+        continue
+      }
+
+      kind := schema.GroupVersionKind{Group: entry.Group, Version: entry.Version, Kind: entry.Kind}
+      mapping, err := resolver(kind)
+      if err != nil {
+        return nil, err
+      }
+
+      live, err := dynClient.Resource(mapping.Resource).Namespace(entry.Namespace).Get(ctx, entry.Name, metav1.GetOptions{})
+      if errors.IsNotFound(err) {
+        continue
+      }
+      if err != nil {
+        return nil, err
+      }
+      if !controllerMisc.IsOwnedBy(live, crdOverlay) {
+        continue
+      }
+
+      result := Result{Kind: kind, Namespace: entry.Namespace, Name: entry.Name, Action: ActionPrune}
+      result.Diff, err = unifiedDiff(live, nil)
+      if err != nil {
+        return nil, err
+      }
+      results = append(results, result)
+    }
+
+    return results, nil
+}
+
+// Print writes the results, one header per resource followed by its diff.
+func Print(out io.Writer, results []Result) {
+  for _, result := range results {
+    fmt.Fprintf(out, "=== %s %s/%s (%s)\n", result.Kind.Kind, result.Namespace, result.Name, result.Action)
+    fmt.Fprint(out, result.Diff)
+  }
+}
+
+// pruneKey identifies a resource independently of its version.
+func pruneKey(group, kind, namespace, name string) string {
+  return fmt.Sprintf("%s/%s/%s/%s", group, kind, namespace, name)
+}
+
+// unifiedDiff returns the unified diff between the live and the desired
+// object, a nil object stands for a missing one.
+func unifiedDiff(live, desired *unstructured.Unstructured) (string, error) {
+  liveYAML, err := objectYAML(live)
+  if err != nil {
+    return "", err
+  }
+  desiredYAML, err := objectYAML(desired)
+  if err != nil {
+    return "", err
+  }
+
+  return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+    A:        difflib.SplitLines(liveYAML),
+    B:        difflib.SplitLines(desiredYAML),
+    FromFile: "live",
+    ToFile:   "rendered",
+    Context:  3,
+  })
+}
+
+// objectYAML marshals an object without the fields that only add noise.
+func objectYAML(object *unstructured.Unstructured) (string, error) {
+  if object == nil {
+    return "", nil
+  }
+  object = object.DeepCopy()
+  object.SetManagedFields(nil)
+
+  objectYAML, err := yaml.Marshal(object.Object)
+  if err != nil {
+    return "", err
+  }
+  return string(objectYAML), nil
+}
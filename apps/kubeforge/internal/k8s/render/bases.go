@@ -0,0 +1,108 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// An Overlay may inherit the data of base Overlays of its namespace,
+// referenced through `spec.bases`. The bases are merged in order
+// between the source configuration and the data of the overlay, the
+// bases of a base ahead of it, each base once:
+//
+//   spec:
+//     bases:
+//       - name: gpu-profile
+//       - name: debug-sidecar
+//
+// Only the data of a base is inherited, its patches and values are
+// not. A base referencing back to the overlay is a cycle. A base
+// marked `spec.abstract: true` is a profile, it is neither rendered
+// nor applied on its own.
+//
+// ############################################################
+
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+)
+
+// OverlayGetter returns an Overlay of a namespace by name.
+type OverlayGetter func(namespace, name string) (*crdv1.Overlay, error)
+
+// Bases returns the base Overlays of an overlay in merge order.
+func Bases(crdOverlay *crdv1.Overlay, getOverlay OverlayGetter) ([]*crdv1.Overlay, error) {
+  if len(crdOverlay.Spec.Bases) == 0 {
+    return nil, nil
+  }
+  if getOverlay == nil {
+    return nil, fmt.Errorf("base overlays can not be resolved")
+  }
+
+  bases := []*crdv1.Overlay{}
+  visited := map[string]bool{}
+  path := []string{crdOverlay.Name}
+
+  var visit func(current *crdv1.Overlay) error
+  visit = func(current *crdv1.Overlay) error {
+    for _, reference := range current.Spec.Bases {
+      for _, name := range path {
+        if name == reference.Name {
+          return fmt.Errorf("base cycle: %s -> %s", strings.Join(path, " -> "), reference.Name)
+        }
+      }
+      if visited[reference.Name] {
+        continue
+      }
+
+      base, err := getOverlay(crdOverlay.Namespace, reference.Name)
+      if err != nil {
+        return fmt.Errorf("base overlay %q: %w", reference.Name, err)
+      }
+
+      path = append(path, reference.Name)
+      if err := visit(base); err != nil {
+        return err
+      }
+      path = path[:len(path)-1]
+
+      visited[reference.Name] = true
+      bases = append(bases, base)
+    }
+    return nil
+  }
+
+  if err := visit(crdOverlay); err != nil {
+    return nil, err
+  }
+  return bases, nil
+}
+
+// UnmarshalLayers unmarshals the data of the bases and of the overlay, in
+// merge order.
+func UnmarshalLayers(
+  crdOverlay *crdv1.Overlay,
+  getOverlay OverlayGetter,
+  environment Environment,
+) (
+  []map[string]interface{},
+  error,
+) {
+
+    bases, err := Bases(crdOverlay, getOverlay)
+    if err != nil {
+      return nil, err
+    }
+
+    layers := make([]map[string]interface{}, 0, len(bases)+1)
+    for _, base := range append(bases, crdOverlay) {
+      data, err := UnmarshalOverlay(base, environment.Overlay)
+      if err != nil {
+        return nil, fmt.Errorf("overlay %q: %w", base.Name, err)
+      }
+      layers = append(layers, data)
+    }
+    return layers, nil
+}
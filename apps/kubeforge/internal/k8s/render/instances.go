@@ -0,0 +1,241 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// An entry of the data may render several instances of itself
+// through its `instances` block, either a count or a list of named
+// instances with values merged on top of the entry:
+//
+//   Pod:
+//     - metadata:
+//         name: worker
+//       instances:
+//         count: 3            # worker-0, worker-1, worker-2
+//
+//     - metadata:
+//         name: db
+//       instances:
+//         - name: primary     # db-primary
+//         - name: replica     # db-replica
+//           values:
+//             spec: ...
+//
+// Every instance is a resource of its own carrying the
+// `kubeforge.sh/instance` label, lowering the count or removing a
+// named instance prunes it. An entry renders at most MaxInstances
+// instances.
+//
+// ############################################################
+
+package render
+
+import (
+	"fmt"
+	"strconv"
+
+	yamlMisc "kubeforge/internal/ops/yaml/misc"
+)
+
+const (
+  // instancesKey is the key of the instances block of an entry
+  instancesKey = "instances"
+
+  // InstanceLabel holds the index or name of an instance
+  InstanceLabel = "kubeforge.sh/instance"
+
+  // MaxInstances is the maximum number of instances of an entry
+  MaxInstances = 100
+)
+
+// instance is a single instance of an entry.
+type instance struct {
+  key    string
+  values interface{}
+}
+
+// expandInstances replaces the entries with an instances block by their
+// instances.
+func expandInstances(dataMergedMap map[string]interface{}, schema yamlMisc.MergeSchema) error {
+  for resourceType, value := range dataMergedMap {
+    resourceList, ok := value.([]interface{})
+    if !ok {
+      continue
+    }
+
+    itemSchema := fieldSchema(schema, resourceType)
+    if itemSchema != nil {
+      itemSchema = itemSchema.Items()
+    }
+
+    expanded := make([]interface{}, 0, len(resourceList))
+    for _, resourceDefinition := range resourceList {
+      definition, ok := resourceDefinition.(map[string]interface{})
+      if !ok {
+        expanded = append(expanded, resourceDefinition)
+        continue
+      }
+      rawInstances, exists := definition[instancesKey]
+      if !exists {
+        expanded = append(expanded, resourceDefinition)
+        continue
+      }
+
+      instances, err := parseInstances(rawInstances)
+      if err != nil {
+        return fmt.Errorf("resource type '%v': %v", resourceType, err)
+      }
+      for _, instance := range instances {
+        instanceDefinition, err := instantiate(definition, instance, itemSchema)
+        if err != nil {
+          return fmt.Errorf("resource type '%v': %v", resourceType, err)
+        }
+        expanded = append(expanded, instanceDefinition)
+      }
+    }
+    dataMergedMap[resourceType] = expanded
+  }
+  return nil
+}
+
+// fieldSchema returns the schema of a field, nil when unknown.
+func fieldSchema(schema yamlMisc.MergeSchema, key string) yamlMisc.MergeSchema {
+  if schema == nil {
+    return nil
+  }
+  return schema.Field(key)
+}
+
+// parseInstances reads an instances block, `{count: N}` or a list of
+// `{name: <name>, values: {...}}`.
+func parseInstances(rawInstances interface{}) ([]instance, error) {
+  switch block := rawInstances.(type) {
+  case map[string]interface{}:
+    count, err := instanceCount(block["count"])
+    if err != nil {
+      return nil, err
+    }
+    instances := make([]instance, 0, count)
+    for index := 0; index < count; index++ {
+      instances = append(instances, instance{key: strconv.Itoa(index)})
+    }
+    return instances, nil
+
+  case []interface{}:
+    if len(block) > MaxInstances {
+      return nil, fmt.Errorf("instances must not exceed %d", MaxInstances)
+    }
+    instances := make([]instance, 0, len(block))
+    seen := map[string]bool{}
+    for _, rawInstance := range block {
+      fields, ok := rawInstance.(map[string]interface{})
+      if !ok {
+        return nil, fmt.Errorf("instances must be objects with a name")
+      }
+      name := fmt.Sprint(fields["name"])
+      if fields["name"] == nil || name == "" {
+        return nil, fmt.Errorf("instances must be objects with a name")
+      }
+      if seen[name] {
+        return nil, fmt.Errorf("instance %q is defined twice", name)
+      }
+      seen[name] = true
+      instances = append(instances, instance{key: name, values: fields["values"]})
+    }
+    return instances, nil
+  }
+  return nil, fmt.Errorf("instances must be a count ({count: N}) or a list of named instances")
+}
+
+// instanceCount reads the count of an instances block.
+func instanceCount(rawCount interface{}) (int, error) {
+  var count int
+  switch value := rawCount.(type) {
+  case int:
+    count = value
+  case int64:
+    count = int(value)
+  case float64:
+    count = int(value)
+    if float64(count) != value {
+      return 0, fmt.Errorf("instances count must be an integer")
+    }
+  default:
+    return 0, fmt.Errorf("instances count must be an integer")
+  }
+  if count < 0 {
+    return 0, fmt.Errorf("instances count must not be negative")
+  }
+  if count > MaxInstances {
+    return 0, fmt.Errorf("instances count must not exceed %d", MaxInstances)
+  }
+  return count, nil
+}
+
+// instantiate returns the definition of a single instance of an entry.
+func instantiate(
+  definition map[string]interface{},
+  instance   instance,
+  schema     yamlMisc.MergeSchema,
+) (
+  map[string]interface{},
+  error,
+) {
+
+    template := map[string]interface{}{}
+    for key, value := range definition {
+      if key != instancesKey {
+        template[key] = value
+      }
+    }
+
+    // Per-instance values are merged on top of the entry
+    merged := template
+    if instance.values != nil {
+      if _, ok := instance.values.(map[string]interface{}); !ok {
+        return nil, fmt.Errorf("values of instance %q must be an object", instance.key)
+      }
+      merged, _ = yamlMisc.StripDirectives(yamlMisc.StructuresMerge(template, instance.values, schema)).(map[string]interface{})
+    }
+
+    // Copy the metadata, the entries share it with the template
+    metadata := map[string]interface{}{}
+    if templateMetadata, ok := merged["metadata"].(map[string]interface{}); ok {
+      for key, value := range templateMetadata {
+        metadata[key] = value
+      }
+    }
+    name, _ := metadata["name"].(string)
+    if name == "" {
+      return nil, fmt.Errorf("instances need a metadata.name")
+    }
+    metadata["name"] = name + "-" + instance.key
+
+    annotations := copyStringMap(metadata["annotations"])
+    if overrideName, ok := annotations["kubeforge.sh/override-name"].(string); ok && overrideName != "" {
+      annotations["kubeforge.sh/override-name"] = overrideName + "-" + instance.key
+      metadata["annotations"] = annotations
+    }
+
+    labels := copyStringMap(metadata["labels"])
+    labels[InstanceLabel] = instance.key
+    metadata["labels"] = labels
+
+    instanceDefinition := map[string]interface{}{}
+    for key, value := range merged {
+      instanceDefinition[key] = value
+    }
+    instanceDefinition["metadata"] = metadata
+    return instanceDefinition, nil
+}
+
+// copyStringMap returns a copy of a map, an empty one when it is not a map.
+func copyStringMap(value interface{}) map[string]interface{} {
+  copied := map[string]interface{}{}
+  if m, ok := value.(map[string]interface{}); ok {
+    for key, item := range m {
+      copied[key] = item
+    }
+  }
+  return copied
+}
@@ -0,0 +1,187 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// instanceEntry returns a Pod entry of the given metadata and instances
+// block.
+func instanceEntry(metadata map[string]interface{}, instances interface{}) map[string]interface{} {
+  return map[string]interface{}{
+    "Pod": []interface{}{
+      map[string]interface{}{
+        "metadata":   metadata,
+        "spec":       map[string]interface{}{"restartPolicy": "Always"},
+        instancesKey: instances,
+      },
+    },
+  }
+}
+
+// namedInstances returns an instances block of the given number of named
+// instances.
+func namedInstances(count int) []interface{} {
+  instances := []interface{}{}
+  for index := 0; index < count; index++ {
+    instances = append(instances, map[string]interface{}{"name": "i" + strings.Repeat("x", index)})
+  }
+  return instances
+}
+
+func TestExpandInstances(t *testing.T) {
+  tests := []struct {
+    name      string
+    metadata  map[string]interface{}
+    instances interface{}
+    want      []map[string]interface{}
+    wantErr   string
+  }{
+    {
+      name:      "count",
+      metadata:  map[string]interface{}{"name": "worker"},
+      instances: map[string]interface{}{"count": 2},
+      want: []map[string]interface{}{
+        {"name": "worker-0", "labels": map[string]interface{}{InstanceLabel: "0"}},
+        {"name": "worker-1", "labels": map[string]interface{}{InstanceLabel: "1"}},
+      },
+    },
+    {
+      name:      "count of zero",
+      metadata:  map[string]interface{}{"name": "worker"},
+      instances: map[string]interface{}{"count": float64(0)},
+      want:      []map[string]interface{}{},
+    },
+    {
+      name:      "named instances keep the labels of the entry",
+      metadata:  map[string]interface{}{"name": "db", "labels": map[string]interface{}{"app": "db"}},
+      instances: []interface{}{map[string]interface{}{"name": "primary"}, map[string]interface{}{"name": "replica"}},
+      want: []map[string]interface{}{
+        {"name": "db-primary", "labels": map[string]interface{}{"app": "db", InstanceLabel: "primary"}},
+        {"name": "db-replica", "labels": map[string]interface{}{"app": "db", InstanceLabel: "replica"}},
+      },
+    },
+    {
+      name: "override name gets the suffix",
+      metadata: map[string]interface{}{
+        "name":        "web",
+        "annotations": map[string]interface{}{"kubeforge.sh/override-name": "frontend"},
+      },
+      instances: map[string]interface{}{"count": int64(1)},
+      want: []map[string]interface{}{
+        {
+          "name":        "web-0",
+          "annotations": map[string]interface{}{"kubeforge.sh/override-name": "frontend-0"},
+          "labels":      map[string]interface{}{InstanceLabel: "0"},
+        },
+      },
+    },
+    {
+      name:      "count at the maximum",
+      metadata:  map[string]interface{}{"name": "worker"},
+      instances: map[string]interface{}{"count": MaxInstances},
+    },
+    {
+      name:      "count over the maximum",
+      metadata:  map[string]interface{}{"name": "worker"},
+      instances: map[string]interface{}{"count": MaxInstances + 1},
+      wantErr:   "instances count must not exceed 100",
+    },
+    {
+      name:      "named instances over the maximum",
+      metadata:  map[string]interface{}{"name": "worker"},
+      instances: namedInstances(MaxInstances + 1),
+      wantErr:   "instances must not exceed 100",
+    },
+    {
+      name:      "negative count",
+      metadata:  map[string]interface{}{"name": "worker"},
+      instances: map[string]interface{}{"count": -1},
+      wantErr:   "must not be negative",
+    },
+    {
+      name:      "fractional count",
+      metadata:  map[string]interface{}{"name": "worker"},
+      instances: map[string]interface{}{"count": 1.5},
+      wantErr:   "must be an integer",
+    },
+    {
+      name:      "instance without name",
+      metadata:  map[string]interface{}{"name": "db"},
+      instances: []interface{}{map[string]interface{}{"values": map[string]interface{}{}}},
+      wantErr:   "instances must be objects with a name",
+    },
+    {
+      name:      "instance defined twice",
+      metadata:  map[string]interface{}{"name": "db"},
+      instances: []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "a"}},
+      wantErr:   `instance "a" is defined twice`,
+    },
+    {
+      name:      "entry without name",
+      metadata:  map[string]interface{}{},
+      instances: map[string]interface{}{"count": 1},
+      wantErr:   "instances need a metadata.name",
+    },
+    {
+      name:      "invalid block",
+      metadata:  map[string]interface{}{"name": "worker"},
+      instances: "3",
+      wantErr:   "instances must be a count",
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      data := instanceEntry(test.metadata, test.instances)
+      err := expandInstances(data, nil)
+      if test.wantErr != "" {
+        if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+          t.Fatalf("expandInstances() error = %v, want %q", err, test.wantErr)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("expandInstances() error = %v", err)
+      }
+
+      entries := data["Pod"].([]interface{})
+      if test.want == nil {
+        if len(entries) != MaxInstances {
+          t.Errorf("expandInstances() = %d instances, want %d", len(entries), MaxInstances)
+        }
+        return
+      }
+      got := []map[string]interface{}{}
+      for _, entry := range entries {
+        definition := entry.(map[string]interface{})
+        if _, exists := definition[instancesKey]; exists {
+          t.Errorf("instance %v keeps the instances block", definition["metadata"])
+        }
+        got = append(got, definition["metadata"].(map[string]interface{}))
+      }
+      if !reflect.DeepEqual(got, test.want) {
+        t.Errorf("expandInstances() metadata = %v, want %v", got, test.want)
+      }
+    })
+  }
+}
+
+func TestExpandInstancesValues(t *testing.T) {
+  data := instanceEntry(map[string]interface{}{"name": "db"}, []interface{}{
+    map[string]interface{}{"name": "primary"},
+    map[string]interface{}{"name": "replica", "values": map[string]interface{}{"spec": map[string]interface{}{"restartPolicy": "Never"}}},
+  })
+  if err := expandInstances(data, nil); err != nil {
+    t.Fatalf("expandInstances() error = %v", err)
+  }
+
+  want := []string{"Always", "Never"}
+  for index, entry := range data["Pod"].([]interface{}) {
+    spec := entry.(map[string]interface{})["spec"].(map[string]interface{})
+    if spec["restartPolicy"] != want[index] {
+      t.Errorf("instance %d restartPolicy = %v, want %v", index, spec["restartPolicy"], want[index])
+    }
+  }
+}
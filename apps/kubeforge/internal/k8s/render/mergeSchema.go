@@ -0,0 +1,153 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// The overlay data maps resource types to lists of resources. The
+// resources of a type are matched by `metadata.name`, the lists
+// inside of them are merged according to the merge rules of the
+// source configuration and the merge schema of the type (see
+// `controllerMisc.MergeSchemas`). The merge rules are kept under the
+// reserved `mergeRules` key, per resource type or for all of them
+// under `*`. A resource type is any spelling of a kind (`Pod`,
+// `v1/Pod`, `pods`), an unknown one is an error:
+//
+//   mergeRules:
+//     Pod:
+//       spec.tolerations: {key: key}
+//       spec.containers[].ports: {key: containerPort}
+//     "*":
+//       metadata.finalizers: {strategy: unique}
+//
+// ############################################################
+
+package render
+
+import (
+	"fmt"
+
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
+	yamlMisc "kubeforge/internal/ops/yaml/misc"
+)
+
+// mergeRulesKey is the key of the merge rules in the source configuration.
+const mergeRulesKey = "mergeRules"
+
+// allResourceTypes holds the merge rules of every resource type.
+const allResourceTypes = "*"
+
+// extractMergeRules removes the merge rules from the source configuration
+// and returns them per kind.
+func extractMergeRules(sourceData map[string]interface{}, resolver Resolver) (map[string]yamlMisc.MergeRules, error) {
+  raw, exists := sourceData[mergeRulesKey]
+  if !exists {
+    return nil, nil
+  }
+  delete(sourceData, mergeRulesKey)
+
+  resourceTypes, ok := raw.(map[string]interface{})
+  if !ok {
+    return nil, fmt.Errorf("%s must map resource types to rules", mergeRulesKey)
+  }
+
+  rules := map[string]yamlMisc.MergeRules{}
+  for resourceType, rawRules := range resourceTypes {
+    parsed, err := yamlMisc.ParseMergeRules(rawRules)
+    if err != nil {
+      return nil, fmt.Errorf("%s of %q: %w", mergeRulesKey, resourceType, err)
+    }
+
+    key := resourceType
+    if resourceType != allResourceTypes {
+      if key, err = ruleKey(resourceType, resolver); err != nil {
+        return nil, fmt.Errorf("%s of %q: %w", mergeRulesKey, resourceType, err)
+      }
+    }
+    if rules[key] == nil {
+      rules[key] = yamlMisc.MergeRules{}
+    }
+    for path, rule := range parsed {
+      rules[key][path] = rule
+    }
+  }
+  return rules, nil
+}
+
+// ruleKey returns the kind a resource type resolves to, the spellings of a
+// kind share their rules.
+func ruleKey(resourceType string, resolver Resolver) (string, error) {
+  if resolver == nil {
+    return resourceType, nil
+  }
+  mapping, err := resolver(resourceType)
+  if err != nil {
+    return "", err
+  }
+  return mapping.GroupVersionKind.GroupKind().String(), nil
+}
+
+// overlayMergeSchema is the merge schema of the overlay data.
+type overlayMergeSchema struct {
+  resolver Resolver
+  schemas  SchemaSource
+  rules    map[string]yamlMisc.MergeRules
+}
+
+func (s overlayMergeSchema) Field(resourceType string) yamlMisc.MergeSchema {
+
+  // Rules of the kind win over the ones of all types
+  kind, err := ruleKey(resourceType, s.resolver)
+  if err != nil {
+    kind = resourceType
+  }
+  rules := yamlMisc.MergeRules{}
+  for _, key := range []string{allResourceTypes, kind} {
+    for path, rule := range s.rules[key] {
+      rules[path] = rule
+    }
+  }
+  return resourceListMergeSchema{resourceType: resourceType, schemas: s.schemas, rules: rules}
+}
+
+func (s overlayMergeSchema) List() (yamlMisc.ListStrategy, []string) {
+  return yamlMisc.ListUnknown, nil
+}
+
+func (s overlayMergeSchema) Items() yamlMisc.MergeSchema {
+  return nil
+}
+
+// resourceListMergeSchema is the merge schema of the resources of a type.
+type resourceListMergeSchema struct {
+  resourceType string
+  schemas      SchemaSource
+  rules        yamlMisc.MergeRules
+}
+
+func (s resourceListMergeSchema) Field(key string) yamlMisc.MergeSchema {
+  return nil
+}
+
+func (s resourceListMergeSchema) List() (yamlMisc.ListStrategy, []string) {
+  return yamlMisc.ListMerge, []string{"metadata.name"}
+}
+
+func (s resourceListMergeSchema) Items() yamlMisc.MergeSchema {
+  var schema yamlMisc.MergeSchema
+  if s.schemas != nil {
+    schema = s.schemas(s.resourceType)
+  }
+  return yamlMisc.WithRules(schema, s.rules)
+}
+
+// SchemeSchemas returns a SchemaSource for the built-in Kubernetes kinds,
+// reading the merge strategies out of their Go types.
+func SchemeSchemas(resolver Resolver) SchemaSource {
+  return func(resourceType string) yamlMisc.MergeSchema {
+    mapping, err := resolver(resourceType)
+    if err != nil {
+      return nil
+    }
+    return controllerMisc.BuiltinMergeSchema(mapping.GroupVersionKind)
+  }
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tolerations returns a list of tolerations keyed by the given keys.
+func tolerations(keys ...string) []interface{} {
+  items := []interface{}{}
+  for _, key := range keys {
+    items = append(items, map[string]interface{}{"key": key, "effect": "NoSchedule"})
+  }
+  return items
+}
+
+// podData returns the data of a single Pod keyed by the resource type.
+func podData(resourceType string, tolerations []interface{}) map[string]interface{} {
+  return map[string]interface{}{
+    resourceType: []interface{}{
+      map[string]interface{}{
+        "metadata": map[string]interface{}{"name": "web"},
+        "spec":     map[string]interface{}{"tolerations": tolerations},
+      },
+    },
+  }
+}
+
+func TestMergeRulesResourceTypes(t *testing.T) {
+  tests := []struct {
+    name         string
+    ruleType     string
+    resourceType string
+    want         []interface{}
+    wantErr      string
+  }{
+    {
+      name:         "rule and data keyed by kind",
+      ruleType:     "Pod",
+      resourceType: "Pod",
+      want:         tolerations("a", "b"),
+    },
+    {
+      name:         "rule keyed by kind, data by resource name",
+      ruleType:     "Pod",
+      resourceType: "pods",
+      want:         tolerations("a", "b"),
+    },
+    {
+      name:         "rule keyed by group version, data by kind",
+      ruleType:     "v1/Pod",
+      resourceType: "Pod",
+      want:         tolerations("a", "b"),
+    },
+    {
+      name:         "rule for all resource types",
+      ruleType:     "*",
+      resourceType: "v1/Pod",
+      want:         tolerations("a", "b"),
+    },
+    {
+      name:         "rule of another kind",
+      ruleType:     "ConfigMap",
+      resourceType: "Pod",
+      want:         tolerations("b"),
+    },
+    {
+      name:         "unknown resource type",
+      ruleType:     "Unknown",
+      resourceType: "Pod",
+      wantErr:      `mergeRules of "Unknown"`,
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      source := podData(test.resourceType, tolerations("a"))
+      source[mergeRulesKey] = map[string]interface{}{
+        test.ruleType: map[string]interface{}{
+          "spec.tolerations": map[string]interface{}{"key": "key"},
+        },
+      }
+      layers := []map[string]interface{}{podData(test.resourceType, tolerations("b"))}
+
+      resolver := SchemeResolver()
+      merged, err := Merge(source, layers, resolver, SchemeSchemas(resolver))
+      if test.wantErr != "" {
+        if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+          t.Fatalf("Merge() error = %v, want %q", err, test.wantErr)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("Merge() error = %v", err)
+      }
+
+      pod := merged[test.resourceType].([]interface{})[0].(map[string]interface{})
+      got := pod["spec"].(map[string]interface{})["tolerations"]
+      if !reflect.DeepEqual(got, test.want) {
+        t.Errorf("tolerations = %v, want %v", got, test.want)
+      }
+    })
+  }
+}
@@ -0,0 +1,178 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Order turns the rendered resources into an apply plan. Resources
+// are ranked by kind (Namespaces and CRDs first, workloads last) and
+// may depend on other resources of the overlay through the
+// `kubeforge.sh/depends-on` annotation, a comma separated list of
+// `Kind/name` using the final names. Every resource waits for the
+// Namespace it lives in, the CRDs of an earlier rank and its
+// declared dependencies to be ready.
+// A dependency cycle is an error.
+//
+// ############################################################
+
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DependsOnAnnotation declares the dependencies of a resource.
+const DependsOnAnnotation = "kubeforge.sh/depends-on"
+
+// kindOrder is the default apply order, kinds not listed come last.
+var kindOrder = []string{
+  "Namespace",
+  "CustomResourceDefinition",
+  "PriorityClass",
+  "StorageClass",
+  "ServiceAccount",
+  "ClusterRole",
+  "ClusterRoleBinding",
+  "Role",
+  "RoleBinding",
+  "ConfigMap",
+  "Secret",
+  "PersistentVolume",
+  "PersistentVolumeClaim",
+  "Service",
+  "Pod",
+  "ReplicaSet",
+  "Deployment",
+  "StatefulSet",
+  "DaemonSet",
+  "Job",
+  "CronJob",
+}
+
+// gates reports whether a resource can not be created before another one of
+// the overlay is ready: its Namespace, or a CRD of an earlier rank.
+func gates(gating, resource Resource) bool {
+  if kindRank(gating.Object.GetKind()) >= kindRank(resource.Object.GetKind()) {
+    return false
+  }
+  switch gating.Object.GetKind() {
+  case "Namespace":
+    return resource.Namespaced && resource.Object.GetNamespace() == gating.Object.GetName()
+  case "CustomResourceDefinition":
+    return true
+  }
+  return false
+}
+
+// Step is a resource of the apply plan.
+type Step struct {
+  Resource  Resource
+  DependsOn []int // plan indexes of the resources to be ready first
+}
+
+// kindRank returns the position of a kind in the default apply order.
+func kindRank(kind string) int {
+  for rank, orderedKind := range kindOrder {
+    if orderedKind == kind {
+      return rank
+    }
+  }
+  return len(kindOrder)
+}
+
+// resourceKey identifies a resource for the depends-on annotation.
+func resourceKey(kind, name string) string {
+  return strings.ToLower(kind) + "/" + name
+}
+
+// Order returns the apply plan of the resources, every step comes after the
+// steps it depends on.
+func Order(resources []Resource) ([]Step, error) {
+
+  // Rank by kind first, the input order is kept within a kind
+  ranked := make([]Resource, len(resources))
+  copy(ranked, resources)
+  sort.SliceStable(ranked, func(i, j int) bool {
+    return kindRank(ranked[i].Object.GetKind()) < kindRank(ranked[j].Object.GetKind())
+  })
+
+  keys := map[string]int{}
+  for index, resource := range ranked {
+    keys[resourceKey(resource.Object.GetKind(), resource.Object.GetName())] = index
+  }
+
+  // Collect the dependencies of each resource
+  dependencies := make([][]int, len(ranked))
+  for index, resource := range ranked {
+    for gatingIndex, gating := range ranked {
+      if gates(gating, resource) {
+        dependencies[index] = append(dependencies[index], gatingIndex)
+      }
+    }
+
+    dependsOn := resource.Object.GetAnnotations()[DependsOnAnnotation]
+    for _, dependency := range strings.Split(dependsOn, ",") {
+      dependency = strings.TrimSpace(dependency)
+      if dependency == "" {
+        continue
+      }
+      kind, name, found := strings.Cut(dependency, "/")
+      if !found || kind == "" || name == "" {
+        return nil, fmt.Errorf("%s %q: invalid %s entry %q, expected Kind/name", resource.Object.GetKind(), resource.Object.GetName(), DependsOnAnnotation, dependency)
+      }
+      dependencyIndex, exists := keys[resourceKey(kind, name)]
+      if !exists {
+        return nil, fmt.Errorf("%s %q depends on %q which is not part of the overlay", resource.Object.GetKind(), resource.Object.GetName(), dependency)
+      }
+      dependencies[index] = append(dependencies[index], dependencyIndex)
+    }
+  }
+
+  // Topological sort, a resource is planned once its dependencies are planned
+  const (
+    unvisited = iota
+    visiting
+    visited
+  )
+  state := make([]int, len(ranked))
+  planned := map[int]int{}
+  steps := []Step{}
+
+  var visit func(index int, path []string) error
+  visit = func(index int, path []string) error {
+    resource := ranked[index].Object
+    path = append(path, resource.GetKind()+"/"+resource.GetName())
+
+    switch state[index] {
+    case visited:
+      return nil
+    case visiting:
+      return fmt.Errorf("dependency cycle: %s", strings.Join(path, " -> "))
+    }
+
+    state[index] = visiting
+    for _, dependency := range dependencies[index] {
+      if err := visit(dependency, path); err != nil {
+        return err
+      }
+    }
+    state[index] = visited
+
+    step := Step{Resource: ranked[index]}
+    for _, dependency := range dependencies[index] {
+      step.DependsOn = append(step.DependsOn, planned[dependency])
+    }
+    planned[index] = len(steps)
+    steps = append(steps, step)
+    return nil
+  }
+
+  for index := range ranked {
+    if err := visit(index, nil); err != nil {
+      return nil, err
+    }
+  }
+
+  return steps, nil
+}
@@ -0,0 +1,149 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// orderResource returns a rendered resource for the order tests.
+func orderResource(kind, namespace, name, dependsOn string) Resource {
+  object := &unstructured.Unstructured{}
+  object.SetKind(kind)
+  object.SetName(name)
+  object.SetNamespace(namespace)
+  if dependsOn != "" {
+    object.SetAnnotations(map[string]string{DependsOnAnnotation: dependsOn})
+  }
+  return Resource{Namespaced: namespace != "", Object: object}
+}
+
+// stepNames returns the `Kind/name` of the steps with their dependencies.
+func stepNames(steps []Step) []string {
+  names := []string{}
+  for _, step := range steps {
+    name := step.Resource.Object.GetKind() + "/" + step.Resource.Object.GetName()
+    dependencies := []string{}
+    for _, dependency := range step.DependsOn {
+      dependencies = append(dependencies, steps[dependency].Resource.Object.GetName())
+    }
+    if len(dependencies) > 0 {
+      name += " <- " + strings.Join(dependencies, ",")
+    }
+    names = append(names, name)
+  }
+  return names
+}
+
+func TestOrder(t *testing.T) {
+  tests := []struct {
+    name      string
+    resources []Resource
+    want      []string
+    wantErr   string
+  }{
+    {
+      name: "kind order",
+      resources: []Resource{
+        orderResource("Pod", "default", "web", ""),
+        orderResource("Service", "default", "web", ""),
+        orderResource("ConfigMap", "default", "config", ""),
+        orderResource("Custom", "default", "custom", ""),
+      },
+      want: []string{"ConfigMap/config", "Service/web", "Pod/web", "Custom/custom"},
+    },
+    {
+      name: "input order kept within a kind",
+      resources: []Resource{
+        orderResource("ConfigMap", "default", "b", ""),
+        orderResource("ConfigMap", "default", "a", ""),
+      },
+      want: []string{"ConfigMap/b", "ConfigMap/a"},
+    },
+    {
+      name: "depends-on comes first",
+      resources: []Resource{
+        orderResource("ConfigMap", "default", "first", "Pod/web"),
+        orderResource("Pod", "default", "web", ""),
+      },
+      want: []string{"Pod/web", "ConfigMap/first <- web"},
+    },
+    {
+      name: "depends-on is case insensitive on the kind and trims entries",
+      resources: []Resource{
+        orderResource("Pod", "default", "web", " configmap/a , ConfigMap/b "),
+        orderResource("ConfigMap", "default", "a", ""),
+        orderResource("ConfigMap", "default", "b", ""),
+      },
+      want: []string{"ConfigMap/a", "ConfigMap/b", "Pod/web <- a,b"},
+    },
+    {
+      name: "resources wait for their own namespace only",
+      resources: []Resource{
+        orderResource("Pod", "team-a", "web", ""),
+        orderResource("Pod", "team-b", "db", ""),
+        orderResource("Namespace", "", "team-a", ""),
+        orderResource("Namespace", "", "team-b", ""),
+        orderResource("ClusterRole", "", "reader", ""),
+      },
+      want: []string{"Namespace/team-a", "Namespace/team-b", "ClusterRole/reader", "Pod/web <- team-a", "Pod/db <- team-b"},
+    },
+    {
+      name: "resources wait for the CRDs of an earlier rank",
+      resources: []Resource{
+        orderResource("Custom", "default", "custom", ""),
+        orderResource("CustomResourceDefinition", "", "customs.example.com", ""),
+      },
+      want: []string{"CustomResourceDefinition/customs.example.com", "Custom/custom <- customs.example.com"},
+    },
+    {
+      name: "dependency cycle",
+      resources: []Resource{
+        orderResource("ConfigMap", "default", "a", "ConfigMap/b"),
+        orderResource("ConfigMap", "default", "b", "ConfigMap/a"),
+      },
+      wantErr: "dependency cycle: ConfigMap/a -> ConfigMap/b -> ConfigMap/a",
+    },
+    {
+      name: "self dependency",
+      resources: []Resource{
+        orderResource("ConfigMap", "default", "a", "ConfigMap/a"),
+      },
+      wantErr: "dependency cycle",
+    },
+    {
+      name: "unknown dependency",
+      resources: []Resource{
+        orderResource("Pod", "default", "web", "ConfigMap/missing"),
+      },
+      wantErr: "not part of the overlay",
+    },
+    {
+      name: "invalid dependency",
+      resources: []Resource{
+        orderResource("Pod", "default", "web", "missing"),
+      },
+      wantErr: "expected Kind/name",
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      steps, err := Order(test.resources)
+      if test.wantErr != "" {
+        if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+          t.Fatalf("Order() error = %v, want %q", err, test.wantErr)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("Order() error = %v", err)
+      }
+      if got := stepNames(steps); !reflect.DeepEqual(got, test.want) {
+        t.Errorf("Order() = %v, want %v", got, test.want)
+      }
+    })
+  }
+}
@@ -0,0 +1,258 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Patches of `spec.patches` edit the rendered resources where a
+// merge can not, e.g. to insert into a list at an index. Each patch
+// targets resources by group, version, kind, name and labels and is
+// either a RFC 6902 JSON patch or a strategic merge fragment. A
+// patch which fails, or matches no resource, fails the render. The
+// namespace, owner references and ownership metadata set by the
+// controller can not be patched.
+//
+// ############################################################
+
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+	yamlMisc "kubeforge/internal/ops/yaml/misc"
+
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
+)
+
+// Patch applies the patches in order to the resources matching their
+// target.
+func Patch(
+  resources []Resource,
+  patches   []crdv1.OverlayPatch,
+  schemas   SchemaSource,
+) error {
+
+    for index, patch := range patches {
+      if err := applyPatch(resources, patch, schemas); err != nil {
+        return fmt.Errorf("patch %d (%s): %w", index, describeTarget(patch.Target), err)
+      }
+    }
+    return nil
+}
+
+// applyPatch applies a single patch.
+func applyPatch(
+  resources []Resource,
+  patch     crdv1.OverlayPatch,
+  schemas   SchemaSource,
+) error {
+
+    hasJSONPatch := len(patch.JSONPatch) > 0
+    hasStrategicMerge := patch.StrategicMerge != nil && len(patch.StrategicMerge.Raw) > 0
+    if hasJSONPatch == hasStrategicMerge {
+      return fmt.Errorf("exactly one of jsonPatch and strategicMerge must be set")
+    }
+
+    selector := labels.Everything()
+    if patch.Target.LabelSelector != "" {
+      var err error
+      selector, err = labels.Parse(patch.Target.LabelSelector)
+      if err != nil {
+        return fmt.Errorf("invalid label selector: %w", err)
+      }
+    }
+
+    matched := 0
+    for index := range resources {
+      object := resources[index].Object
+      if !targetMatches(patch.Target, selector, object) {
+        continue
+      }
+      matched++
+
+      var patched map[string]interface{}
+      var err error
+      if hasJSONPatch {
+        patched, err = applyJSONPatch(object.Object, patch.JSONPatch)
+      } else {
+        patched, err = applyStrategicMerge(object, patch.StrategicMerge.Raw, schemas)
+      }
+      if err != nil {
+        return fmt.Errorf("%s %q: %w", object.GetKind(), object.GetName(), err)
+      }
+
+      patchedObject := &unstructured.Unstructured{Object: patched}
+      if patchedObject.GroupVersionKind() != object.GroupVersionKind() {
+        return fmt.Errorf("%s %q: a patch can not change the apiVersion or kind", object.GetKind(), object.GetName())
+      }
+      if err := checkOwnershipMetadata(object, patchedObject); err != nil {
+        return fmt.Errorf("%s %q: %w", object.GetKind(), object.GetName(), err)
+      }
+      if err := refreshAppliedConfiguration(patchedObject); err != nil {
+        return err
+      }
+      resources[index].Object = patchedObject
+    }
+
+    if matched == 0 {
+      return fmt.Errorf("no rendered resource matches the target")
+    }
+    return nil
+}
+
+// applyJSONPatch applies RFC 6902 operations to an object.
+func applyJSONPatch(object map[string]interface{}, operations []crdv1.JSONPatchOperation) (map[string]interface{}, error) {
+  document, err := json.Marshal(object)
+  if err != nil {
+    return nil, err
+  }
+  operationsJSON, err := json.Marshal(jsonPatchOperations(operations))
+  if err != nil {
+    return nil, err
+  }
+
+  decoded, err := jsonpatch.DecodePatch(operationsJSON)
+  if err != nil {
+    return nil, fmt.Errorf("invalid JSON patch: %w", err)
+  }
+  patchedDocument, err := decoded.Apply(document)
+  if err != nil {
+    return nil, err
+  }
+
+  patched := map[string]interface{}{}
+  if err := json.Unmarshal(patchedDocument, &patched); err != nil {
+    return nil, err
+  }
+  return patched, nil
+}
+
+// jsonPatchOperation is the RFC 6902 form of an operation.
+type jsonPatchOperation struct {
+  Op    string          `json:"op"`
+  Path  string          `json:"path"`
+  From  string          `json:"from,omitempty"`
+  Value json.RawMessage `json:"value,omitempty"`
+}
+
+// jsonPatchOperations converts the operations of a patch, add, replace and
+// test always carry a value, a missing or null one being null.
+func jsonPatchOperations(operations []crdv1.JSONPatchOperation) []jsonPatchOperation {
+  converted := make([]jsonPatchOperation, 0, len(operations))
+  for _, operation := range operations {
+    patchOperation := jsonPatchOperation{Op: operation.Op, Path: operation.Path, From: operation.From}
+    if operation.Value != nil && len(operation.Value.Raw) > 0 {
+      patchOperation.Value = json.RawMessage(operation.Value.Raw)
+    }
+    switch operation.Op {
+    case "add", "replace", "test":
+      if patchOperation.Value == nil {
+        patchOperation.Value = json.RawMessage("null")
+      }
+    }
+    converted = append(converted, patchOperation)
+  }
+  return converted
+}
+
+// applyStrategicMerge merges a fragment into an object according to the
+// merge schema of its kind.
+func applyStrategicMerge(object *unstructured.Unstructured, fragment []byte, schemas SchemaSource) (map[string]interface{}, error) {
+  incoming := map[string]interface{}{}
+  if err := json.Unmarshal(fragment, &incoming); err != nil {
+    return nil, fmt.Errorf("invalid strategic merge fragment: %w", err)
+  }
+
+  var schema yamlMisc.MergeSchema
+  if schemas != nil {
+    kind := object.GroupVersionKind()
+    schema = schemas(kind.GroupVersion().String() + "/" + kind.Kind)
+  }
+
+  merged, ok := yamlMisc.StripDirectives(yamlMisc.StructuresMerge(object.Object, incoming, schema)).(map[string]interface{})
+  if !ok {
+    return nil, fmt.Errorf("strategic merge fragment must be an object")
+  }
+  return merged, nil
+}
+
+// checkOwnershipMetadata refuses patches changing where a resource lives or
+// who owns it.
+func checkOwnershipMetadata(object, patchedObject *unstructured.Unstructured) error {
+  if patchedObject.GetNamespace() != object.GetNamespace() {
+    return fmt.Errorf("a patch can not change the namespace")
+  }
+  if !equality.Semantic.DeepEqual(patchedObject.GetOwnerReferences(), object.GetOwnerReferences()) {
+    return fmt.Errorf("a patch can not change the owner references")
+  }
+  if patchedObject.GetLabels()[controllerMisc.OverlayUIDLabel] != object.GetLabels()[controllerMisc.OverlayUIDLabel] {
+    return fmt.Errorf("a patch can not change the %s label", controllerMisc.OverlayUIDLabel)
+  }
+  if patchedObject.GetAnnotations()[controllerMisc.OverlayAnnotation] != object.GetAnnotations()[controllerMisc.OverlayAnnotation] {
+    return fmt.Errorf("a patch can not change the %s annotation", controllerMisc.OverlayAnnotation)
+  }
+  return nil
+}
+
+// refreshAppliedConfiguration records the patched object in its applied
+// configuration annotation, so a changed patch is applied again.
+func refreshAppliedConfiguration(object *unstructured.Unstructured) error {
+  annotations := object.GetAnnotations()
+  if annotations == nil {
+    annotations = map[string]string{}
+  }
+  delete(annotations, appliedConfigurationAnnotation)
+
+  unannotated := object.DeepCopy()
+  unannotated.SetAnnotations(annotations)
+  marshaledData, err := json.Marshal(unannotated.Object)
+  if err != nil {
+    return err
+  }
+
+  annotations[appliedConfigurationAnnotation] = string(marshaledData)
+  object.SetAnnotations(annotations)
+  return nil
+}
+
+// targetMatches reports whether an object is selected by the target.
+func targetMatches(target crdv1.OverlayPatchTarget, selector labels.Selector, object *unstructured.Unstructured) bool {
+  kind := object.GroupVersionKind()
+  switch {
+  case target.Group != "" && target.Group != kind.Group:
+    return false
+  case target.Version != "" && target.Version != kind.Version:
+    return false
+  case target.Kind != "" && target.Kind != kind.Kind:
+    return false
+  case target.Name != "" && target.Name != object.GetName():
+    return false
+  }
+  return selector.Matches(labels.Set(object.GetLabels()))
+}
+
+// describeTarget returns a readable form of a target.
+func describeTarget(target crdv1.OverlayPatchTarget) string {
+  description := target.Kind
+  if description == "" {
+    description = "*"
+  }
+  if groupVersion := strings.Trim(target.Group+"/"+target.Version, "/"); groupVersion != "" {
+    description = groupVersion + " " + description
+  }
+  if target.Name != "" {
+    description += " " + target.Name
+  }
+  if target.LabelSelector != "" {
+    description += " [" + target.LabelSelector + "]"
+  }
+  return description
+}
@@ -0,0 +1,387 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Package render turns a source configuration and an Overlay into
+// the final Kubernetes resources. It holds the whole merge pipeline
+// (unmarshal, merge by name, templates, metadata and the
+// `kubeforge.sh/override-name` handling) so the controller and the
+// offline `kubeforge render` command produce the same output.
+//
+// Resource types (the top-level keys of the data) are resolved by a
+// Resolver, backed by a cached RESTMapper in the controller and by the
+// client-go scheme when no cluster is available.
+//
+// ############################################################
+
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	pkgRuntime "k8s.io/apimachinery/pkg/runtime"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+	yaml "kubeforge/internal/ops/yaml"
+	yamlMisc "kubeforge/internal/ops/yaml/misc"
+
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
+)
+
+// appliedConfigurationAnnotation holds the rendered configuration of a
+// resource, a resource is only applied again when it changes.
+const appliedConfigurationAnnotation = "kubeforge.sh/last-applied-configuration"
+
+// Resolver resolves a resource type (e.g. "Pod" or "configmaps") into its
+// resource, kind and scope.
+type Resolver func(resourceType string) (*meta.RESTMapping, error)
+
+// SchemaSource returns the merge schema of a resource type, nil when it is
+// unknown.
+type SchemaSource func(resourceType string) yamlMisc.MergeSchema
+
+// Environment holds the variable substitution of the source configuration
+// file and of the data provided by tenants, the overlay data and the
+// OverlaySources. A nil Expander disables the substitution.
+type Environment struct {
+  Source  *yaml.Expander
+  Overlay *yaml.Expander
+}
+
+// SourceExpander returns the expander of the source an overlay renders on
+// top of, OverlaySources are tenant data like the overlay itself.
+func (environment Environment) SourceExpander(crdOverlay *crdv1.Overlay) *yaml.Expander {
+  if crdOverlay.Spec.SourceRef != nil {
+    return environment.Overlay
+  }
+  return environment.Source
+}
+
+// Resource is a single rendered resource ready to be applied.
+type Resource struct {
+  Schema     schema.GroupVersionResource
+  Namespaced bool
+  Object     *unstructured.Unstructured
+}
+
+// Render runs the whole pipeline for one overlay on top of the source
+// configuration (a file path or the YAML content itself).
+func Render(
+  ctx                 context.Context,
+  sourceConfiguration string,
+  crdOverlay          *crdv1.Overlay,
+  resolver            Resolver,
+  schemas             SchemaSource,
+  environment         Environment,
+  getOverlay          OverlayGetter,
+) (
+  []Resource,
+  error,
+) {
+
+    layers, err := UnmarshalLayers(crdOverlay, getOverlay, environment)
+    if err != nil {
+      return nil, err
+    }
+
+    sourceData, err := UnmarshalSource(sourceConfiguration, environment.SourceExpander(crdOverlay))
+    if err != nil {
+      return nil, err
+    }
+
+    dataMergedMap, err := Merge(sourceData, layers, resolver, schemas)
+    if err != nil {
+      return nil, err
+    }
+
+    if err := Template(ctx, dataMergedMap, crdOverlay); err != nil {
+      return nil, err
+    }
+
+    objectMetadata, err := Metadata(crdOverlay)
+    if err != nil {
+      return nil, err
+    }
+
+    resources, err := Resources(dataMergedMap, objectMetadata, resolver)
+    if err != nil {
+      return nil, err
+    }
+
+    if err := Patch(resources, crdOverlay.Spec.Patches, schemas); err != nil {
+      return nil, err
+    }
+
+    // Return the resources in apply order
+    steps, err := Order(resources)
+    if err != nil {
+      return nil, err
+    }
+    ordered := make([]Resource, 0, len(steps))
+    for _, step := range steps {
+      ordered = append(ordered, step.Resource)
+    }
+    return ordered, nil
+}
+
+// UnmarshalOverlay unmarshals the custom YAML data from the overlay.
+func UnmarshalOverlay(crdOverlay *crdv1.Overlay, expander *yaml.Expander) (map[string]interface{}, error) {
+  var dataCustom map[string]interface{}
+  err := yaml.Unmarshal(string(crdOverlay.Spec.Data.Raw), &dataCustom, expander)
+  if err != nil {
+    return nil, err
+  }
+  return dataCustom, nil
+}
+
+// UnmarshalSource unmarshals the source configuration.
+func UnmarshalSource(sourceConfiguration string, expander *yaml.Expander) (map[string]interface{}, error) {
+  var defaultRaw map[string]interface{}
+  err := yaml.Unmarshal(sourceConfiguration, &defaultRaw, expander)
+  if err != nil {
+    return nil, err
+  }
+  return defaultRaw, nil
+}
+
+// Merge merges the layers of custom YAML, in order, with the source
+// configuration. The resources of a type are matched by name and their lists
+// merged according to the schema of the type, without schemas list items
+// are matched by name. The merge rules of the source configuration come
+// first, the resolver matches them with the resource types of the data.
+// Resources with an instances block are expanded into their instances
+// afterwards.
+func Merge(
+  defaultRaw map[string]interface{},
+  layers     []map[string]interface{},
+  resolver   Resolver,
+  schemas    SchemaSource,
+) (
+  map[string]interface{},
+  error,
+) {
+
+  rules, err := extractMergeRules(defaultRaw, resolver)
+  if err != nil {
+    return nil, err
+  }
+
+  mergeSchema := overlayMergeSchema{resolver: resolver, schemas: schemas, rules: rules}
+  dataMergedMap := defaultRaw
+  for _, dataCustom := range layers {
+    dataMerged := yamlMisc.StructuresMerge(dataMergedMap, dataCustom, mergeSchema)
+
+    // The merge directives never reach the next layer nor the API server
+    dataMerged = yamlMisc.StripDirectives(dataMerged)
+    var ok bool
+    dataMergedMap, ok = dataMerged.(map[string]interface{})
+    if !ok {
+      return nil, fmt.Errorf("error merging YAML: unexpected type %T", dataMerged)
+    }
+  }
+
+  if err := expandInstances(dataMergedMap, mergeSchema); err != nil {
+    return nil, err
+  }
+  return dataMergedMap, nil
+}
+
+// Metadata sets up the default Kubernetes object metadata of the resources
+// rendered for the overlay. Overlays without UID (e.g. read from a file)
+// get no owner reference.
+func Metadata(crdOverlay *crdv1.Overlay) (metav1.ObjectMeta, error) {
+
+  crdOverlayKind := crdOverlay.Kind
+  if crdOverlayKind == "" {
+    re := regexp.MustCompile(`"kind":"([^"]+)"`)
+    match := re.FindStringSubmatch(fmt.Sprint(crdOverlay))
+
+    // Check if a match was found, overlays read back from the API server
+    // (e.g. after a patch) carry no kind at all
+    if len(match) > 1 {
+      crdOverlayKind = match[1]
+    } else {
+      crdOverlayKind = "Overlay"
+    }
+  }
+
+  objectMetadata := metav1.ObjectMeta{
+    Namespace: crdOverlay.Namespace,
+  }
+  if crdOverlay.UID != "" {
+    objectMetadata.OwnerReferences = []metav1.OwnerReference{
+      *metav1.NewControllerRef(crdOverlay, crdv1.SchemeGroupVersion.WithKind(crdOverlayKind)),
+    }
+  }
+
+  return objectMetadata, nil
+}
+
+// Resources builds the resources out of the merged data. Resource types are
+// processed in alphabetical order, resources of a type in their list order.
+// A resource declaring both `apiVersion` and `kind` is resolved by them
+// instead of its resource type.
+func Resources(
+  dataMergedMap  map[string]interface{},
+  objectMetadata metav1.ObjectMeta,
+  resolver       Resolver,
+) (
+  []Resource,
+  error,
+) {
+
+    resourceTypes := make([]string, 0, len(dataMergedMap))
+    for resourceType := range dataMergedMap {
+      resourceTypes = append(resourceTypes, resourceType)
+    }
+    sort.Strings(resourceTypes)
+
+    resources := []Resource{}
+    for _, resourceType := range resourceTypes {
+      resourceList, ok := dataMergedMap[resourceType].([]interface{})
+      if !ok {
+        return nil, fmt.Errorf("resource type '%v' must hold a list of resources", resourceType)
+      }
+
+      // The resource type is only resolved when a resource relies on it
+      var typeMapping *meta.RESTMapping
+      for _, resourceDefinition := range resourceList {
+        mapping, err := resourceMapping(resourceDefinition, resolver)
+        if err != nil {
+          return nil, fmt.Errorf("resource type '%v': %v", resourceType, err)
+        }
+        if mapping == nil {
+          if typeMapping == nil {
+            typeMapping, err = resolver(resourceType)
+            if err != nil {
+              return nil, err
+            }
+          }
+          mapping = typeMapping
+        }
+
+          // Cluster-scoped resources have no namespace
+        namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+        resourceMetadata := objectMetadata
+        if !namespaced {
+          resourceMetadata = clusterScopedMetadata(objectMetadata)
+        }
+
+        object, err := Object(resourceDefinition, mapping.GroupVersionKind, resourceMetadata)
+        if err != nil {
+          return nil, fmt.Errorf("resource type '%v': %v", resourceType, err)
+        }
+        resources = append(resources, Resource{Schema: mapping.Resource, Namespaced: namespaced, Object: object})
+      }
+    }
+
+    return resources, nil
+}
+
+// clusterScopedMetadata turns the metadata of the overlay resources into the
+// one of a cluster-scoped resource: no namespace, and the owner recorded in
+// a label and an annotation as it can not be referenced by an
+// OwnerReference.
+func clusterScopedMetadata(objectMetadata metav1.ObjectMeta) metav1.ObjectMeta {
+  clusterMetadata := metav1.ObjectMeta{}
+  for _, ownerReference := range objectMetadata.OwnerReferences {
+    clusterMetadata.Labels = map[string]string{
+      controllerMisc.OverlayUIDLabel: string(ownerReference.UID),
+    }
+    clusterMetadata.Annotations = map[string]string{
+      controllerMisc.OverlayAnnotation: objectMetadata.Namespace + "/" + ownerReference.Name,
+    }
+  }
+  return clusterMetadata
+}
+
+// resourceMapping resolves a resource by its own `apiVersion` and `kind`, it
+// returns nil when the resource does not declare both.
+func resourceMapping(resourceDefinition interface{}, resolver Resolver) (*meta.RESTMapping, error) {
+  definition, ok := resourceDefinition.(map[string]interface{})
+  if !ok {
+    return nil, nil
+  }
+  apiVersion, _ := definition["apiVersion"].(string)
+  kind, _ := definition["kind"].(string)
+  if apiVersion == "" || kind == "" {
+    return nil, nil
+  }
+  return resolver(apiVersion + "/" + kind)
+}
+
+// Object converts a single resource definition into an unstructured object,
+// sets its metadata and applies the `kubeforge.sh/override-name` annotation.
+func Object(
+  resourceDefinition interface{},
+  kind               schema.GroupVersionKind,
+  objectMetadata     metav1.ObjectMeta,
+) (
+  *unstructured.Unstructured,
+  error,
+) {
+
+    objMeta, err := pkgRuntime.DefaultUnstructuredConverter.ToUnstructured(&resourceDefinition)
+    if err != nil {
+      return nil, fmt.Errorf("failed to convert resource to unstructured format: %v", err)
+    }
+
+    marshaledData, _ := json.Marshal(objMeta)
+    appliedConfiguration := strings.ReplaceAll(string(marshaledData), "\n", " ")
+    metadataAnnotations := map[string]string{
+      appliedConfigurationAnnotation: appliedConfiguration,
+    }
+
+    createdResource := &unstructured.Unstructured{Object: objMeta}
+    createdResource.SetNamespace(objectMetadata.Namespace)
+    createdResource.SetGroupVersionKind(kind)
+
+    for key, value := range objectMetadata.Annotations {
+      metadataAnnotations[key] = value
+    }
+
+    createdAnnotations := createdResource.GetAnnotations()
+    if createdAnnotations != nil {
+      for key, value := range metadataAnnotations {
+        createdAnnotations[key] = value
+      }
+      createdResource.SetAnnotations(createdAnnotations)
+    } else {
+      createdResource.SetAnnotations(metadataAnnotations)
+    }
+
+    if len(objectMetadata.Labels) > 0 {
+      createdLabels := createdResource.GetLabels()
+      if createdLabels == nil {
+        createdLabels = map[string]string{}
+      }
+      for key, value := range objectMetadata.Labels {
+        createdLabels[key] = value
+      }
+      createdResource.SetLabels(createdLabels)
+    }
+
+    createdResource.SetOwnerReferences(objectMetadata.OwnerReferences)
+
+    overrideNameAnnotation := createdResource.GetAnnotations()["kubeforge.sh/override-name"]
+    if overrideNameAnnotation != "" {
+      createdResource.SetName(overrideNameAnnotation)
+    }
+
+    if createdResource.GetName() == "" {
+      return nil, fmt.Errorf("resource has no metadata.name")
+    }
+
+    return createdResource, nil
+}
@@ -0,0 +1,110 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// SchemeResolver resolves resource types without a cluster, out
+// of the built-in kinds registered in the client-go scheme. The
+// same keys as in the controller are accepted, a bare kind defined
+// by several groups (e.g. Event) is ambiguous. The scheme does not
+// know the scope of a kind, the built-in cluster-scoped kinds are
+// listed below.
+//
+// ############################################################
+
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	controllerMisc "kubeforge/internal/k8s/controller/misc"
+)
+
+// clusterScopedKinds are the built-in kinds which are not namespaced.
+var clusterScopedKinds = map[string]bool{
+  "APIService":                       true,
+  "CertificateSigningRequest":        true,
+  "ClusterRole":                      true,
+  "ClusterRoleBinding":               true,
+  "ClusterTrustBundle":               true,
+  "ComponentStatus":                  true,
+  "CSIDriver":                        true,
+  "CSINode":                          true,
+  "CustomResourceDefinition":         true,
+  "DeviceClass":                      true,
+  "FlowSchema":                       true,
+  "IngressClass":                     true,
+  "IPAddress":                        true,
+  "MutatingWebhookConfiguration":     true,
+  "Namespace":                        true,
+  "Node":                             true,
+  "PersistentVolume":                 true,
+  "PriorityClass":                    true,
+  "PriorityLevelConfiguration":       true,
+  "ResourceSlice":                    true,
+  "RuntimeClass":                     true,
+  "ServiceCIDR":                      true,
+  "StorageClass":                     true,
+  "StorageVersionMigration":          true,
+  "ValidatingAdmissionPolicy":        true,
+  "ValidatingAdmissionPolicyBinding": true,
+  "ValidatingWebhookConfiguration":   true,
+  "VolumeAttachment":                 true,
+  "VolumeAttributesClass":            true,
+}
+
+// removedGroups are groups of the scheme no longer served by the API server,
+// they would make the kinds moved out of them ambiguous.
+var removedGroups = map[string]bool{
+  "extensions": true,
+}
+
+// SchemeResolver returns a Resolver for the built-in Kubernetes kinds.
+func SchemeResolver() Resolver {
+
+  kinds := map[string][]schema.GroupVersionKind{}
+  register := func(groupVersion schema.GroupVersion) {
+    for kindName := range scheme.Scheme.KnownTypes(groupVersion) {
+      kind := groupVersion.WithKind(kindName)
+      plural, _ := meta.UnsafeGuessKindToResource(kind)
+
+      for _, key := range []string{strings.ToLower(kindName), plural.Resource} {
+        kinds[key] = append(kinds[key], kind)
+      }
+    }
+  }
+
+  // Register the groups in their priority order, preferred versions first
+  for _, groupVersion := range scheme.Scheme.PrioritizedVersionsAllGroups() {
+    if !removedGroups[groupVersion.Group] {
+      register(groupVersion)
+    }
+  }
+
+  return func(resourceType string) (*meta.RESTMapping, error) {
+    parsed, err := controllerMisc.ParseResourceType(resourceType)
+    if err != nil {
+      return nil, err
+    }
+
+    kind, err := controllerMisc.SelectKind(resourceType, parsed, kinds[strings.ToLower(parsed.Name)])
+    if meta.IsNoMatchError(err) {
+      return nil, fmt.Errorf("no built-in kind found for resource: %s", resourceType)
+    }
+    if err != nil {
+      return nil, err
+    }
+    plural, _ := meta.UnsafeGuessKindToResource(kind)
+
+    scope := meta.RESTScopeNamespace
+    if clusterScopedKinds[kind.Kind] {
+      scope = meta.RESTScopeRoot
+    }
+    return &meta.RESTMapping{Resource: plural, GroupVersionKind: kind, Scope: scope}, nil
+  }
+}
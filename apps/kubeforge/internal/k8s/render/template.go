@@ -0,0 +1,341 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// String values of the merged data may hold Go templates, evaluated
+// after the merge with a curated set of functions and no access to
+// the environment or the file system. Templates are off unless the
+// Overlay sets `spec.templates: true`, an entry may turn them on or
+// off for itself with its `templates` key:
+//
+//   .Overlay.Name, .Overlay.Namespace, .Overlay.Labels,
+//   .Overlay.Annotations   the Overlay
+//   .Values                `spec.values` of the Overlay
+//   .Resource.Name         `metadata.name` of the resource
+//   .Resource.Instance     the index or name of an instance
+//
+//   metadata:
+//     name: '{{ .Overlay.Name }}-web'
+//   spec:
+//     containers:
+//       - image: 'nginx:{{ index .Values "tag" | default "stable" }}'
+//
+// Templates render to strings, a missing key is an error (`index`
+// looks up optional ones). Loops are bounded by a step budget and
+// the context, templates can not define other templates.
+//
+// ############################################################
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+)
+
+const (
+  // templateOutputLimit is the maximum length of a rendered template
+  templateOutputLimit = 64 * 1024
+
+  // templateStepLimit is the maximum number of loop iterations of the
+  // templates of an overlay
+  templateStepLimit = 100000
+
+  // templatesKey is the key turning the templates of an entry on or off
+  templatesKey = "templates"
+)
+
+// templateBudget bounds the work of the templates of an overlay.
+type templateBudget struct {
+  ctx   context.Context
+  steps int
+}
+
+// step accounts a loop iteration, it fails once the budget is spent or the
+// context is done.
+func (budget *templateBudget) step() (string, error) {
+  budget.steps++
+  if budget.steps > templateStepLimit {
+    return "", fmt.Errorf("templates exceed %d loop iterations", templateStepLimit)
+  }
+  if err := budget.ctx.Err(); err != nil {
+    return "", err
+  }
+  return "", nil
+}
+
+// Template evaluates the templates of the string values of the merged data
+// and removes the templates key of the entries.
+func Template(ctx context.Context, dataMergedMap map[string]interface{}, crdOverlay *crdv1.Overlay) error {
+  values := map[string]interface{}{}
+  if crdOverlay.Spec.Values != nil && len(crdOverlay.Spec.Values.Raw) > 0 {
+    if err := json.Unmarshal(crdOverlay.Spec.Values.Raw, &values); err != nil {
+      return fmt.Errorf("spec.values must be an object: %v", err)
+    }
+  }
+
+  overlay := map[string]interface{}{
+    "Name":        crdOverlay.Name,
+    "Namespace":   crdOverlay.Namespace,
+    "Labels":      stringMap(crdOverlay.Labels),
+    "Annotations": stringMap(crdOverlay.Annotations),
+  }
+
+  budget := &templateBudget{ctx: ctx}
+  for resourceType, value := range dataMergedMap {
+    resourceList, ok := value.([]interface{})
+    if !ok {
+      continue
+    }
+    for index, resourceDefinition := range resourceList {
+      definition, ok := resourceDefinition.(map[string]interface{})
+      if !ok {
+        continue
+      }
+
+      enabled := crdOverlay.Spec.Templates
+      if rawEnabled, exists := definition[templatesKey]; exists {
+        entryEnabled, ok := rawEnabled.(bool)
+        if !ok {
+          return fmt.Errorf("resource type '%v': %s must be true or false", resourceType, templatesKey)
+        }
+        enabled = entryEnabled
+        definition = copyStringMap(definition)
+        delete(definition, templatesKey)
+        resourceList[index] = definition
+      }
+      if !enabled {
+        continue
+      }
+
+      name, instance := "", ""
+      if metadata, ok := definition["metadata"].(map[string]interface{}); ok {
+        name, _ = metadata["name"].(string)
+        if labels, ok := metadata["labels"].(map[string]interface{}); ok {
+          instance, _ = labels[InstanceLabel].(string)
+        }
+      }
+      context := map[string]interface{}{
+        "Overlay":  overlay,
+        "Values":   values,
+        "Resource": map[string]interface{}{"Name": name, "Instance": instance},
+      }
+
+      evaluated, err := evaluateTemplates(definition, context, budget)
+      if err != nil {
+        return fmt.Errorf("resource type '%v' %q: %v", resourceType, name, err)
+      }
+      resourceList[index] = evaluated
+    }
+  }
+  return nil
+}
+
+// evaluateTemplates evaluates the templates of a structure.
+func evaluateTemplates(value interface{}, context map[string]interface{}, budget *templateBudget) (interface{}, error) {
+  switch v := value.(type) {
+  case map[string]interface{}:
+    evaluated := make(map[string]interface{}, len(v))
+    for key, item := range v {
+      result, err := evaluateTemplates(item, context, budget)
+      if err != nil {
+        return nil, err
+      }
+      evaluated[key] = result
+    }
+    return evaluated, nil
+  case []interface{}:
+    evaluated := make([]interface{}, 0, len(v))
+    for _, item := range v {
+      result, err := evaluateTemplates(item, context, budget)
+      if err != nil {
+        return nil, err
+      }
+      evaluated = append(evaluated, result)
+    }
+    return evaluated, nil
+  case string:
+    if !strings.Contains(v, "{{") {
+      return v, nil
+    }
+    return evaluateTemplate(v, context, budget)
+  }
+  return value, nil
+}
+
+// evaluateTemplate evaluates a single template.
+func evaluateTemplate(text string, context map[string]interface{}, budget *templateBudget) (string, error) {
+  parsed, err := template.New("value").
+    Option("missingkey=error").
+    Funcs(templateFunctions).
+    Funcs(template.FuncMap{"step": budget.step}).
+    Parse(text)
+  if err != nil {
+    return "", fmt.Errorf("template %q: %v", text, err)
+  }
+  if len(parsed.Templates()) > 1 {
+    return "", fmt.Errorf("template %q: templates can not define templates", text)
+  }
+
+  // Every loop iteration takes a step of the budget
+  step, err := template.New("step").Funcs(template.FuncMap{"step": budget.step}).Parse("{{step}}")
+  if err != nil {
+    return "", err
+  }
+  if err := boundLoops(parsed.Tree.Root, step.Tree.Root.Nodes[0]); err != nil {
+    return "", fmt.Errorf("template %q: %v", text, err)
+  }
+
+  output := &limitedBuffer{limit: templateOutputLimit}
+  if err := parsed.Execute(output, context); err != nil {
+    return "", fmt.Errorf("template %q: %v", text, err)
+  }
+  return output.String(), nil
+}
+
+// boundLoops prepends the step action to the body of every range of the
+// tree, invoking other templates is refused.
+func boundLoops(node parse.Node, step parse.Node) error {
+  switch typed := node.(type) {
+  case *parse.ListNode:
+    if typed == nil {
+      return nil
+    }
+    for _, child := range typed.Nodes {
+      if err := boundLoops(child, step); err != nil {
+        return err
+      }
+    }
+  case *parse.RangeNode:
+    if err := boundLoops(typed.List, step); err != nil {
+      return err
+    }
+    if err := boundLoops(typed.ElseList, step); err != nil {
+      return err
+    }
+    typed.List.Nodes = append([]parse.Node{step.Copy()}, typed.List.Nodes...)
+  case *parse.IfNode:
+    return boundBranch(&typed.BranchNode, step)
+  case *parse.WithNode:
+    return boundBranch(&typed.BranchNode, step)
+  case *parse.TemplateNode:
+    return fmt.Errorf("templates can not invoke templates")
+  }
+  return nil
+}
+
+// boundBranch bounds the loops of both lists of a branch.
+func boundBranch(branch *parse.BranchNode, step parse.Node) error {
+  if err := boundLoops(branch.List, step); err != nil {
+    return err
+  }
+  return boundLoops(branch.ElseList, step)
+}
+
+// limitedBuffer fails writes beyond its limit.
+type limitedBuffer struct {
+  bytes.Buffer
+  limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+  if b.Len()+len(p) > b.limit {
+    return 0, fmt.Errorf("output exceeds %d bytes", b.limit)
+  }
+  return b.Buffer.Write(p)
+}
+
+// stringMap converts a string map for the templates.
+func stringMap(m map[string]string) map[string]interface{} {
+  converted := make(map[string]interface{}, len(m))
+  for key, value := range m {
+    converted[key] = value
+  }
+  return converted
+}
+
+// templateFunctions are the functions available to the templates, next to
+// the built-in ones of text/template.
+var templateFunctions = template.FuncMap{
+  "default": func(fallback, value interface{}) interface{} {
+    if value == nil || value == "" {
+      return fallback
+    }
+    return value
+  },
+  "required": func(message string, value interface{}) (interface{}, error) {
+    if value == nil || value == "" {
+      return nil, fmt.Errorf("%s", message)
+    }
+    return value, nil
+  },
+  "lower":      strings.ToLower,
+  "upper":      strings.ToUpper,
+  "trim":       strings.TrimSpace,
+  "trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+  "trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+  "replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+  "contains":   func(substring, s string) bool { return strings.Contains(s, substring) },
+  "hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+  "hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+  "split":      func(separator, s string) []string { return strings.Split(s, separator) },
+  "join": func(separator string, values []interface{}) string {
+    parts := make([]string, 0, len(values))
+    for _, value := range values {
+      parts = append(parts, fmt.Sprint(value))
+    }
+    return strings.Join(parts, separator)
+  },
+  "trunc": func(length int, s string) string {
+    if length >= 0 && len(s) > length {
+      return s[:length]
+    }
+    return s
+  },
+  "quote": strconv.Quote,
+  "toJson": func(value interface{}) (string, error) {
+    encoded, err := json.Marshal(value)
+    return string(encoded), err
+  },
+  "add": func(a, b interface{}) (int, error) { return arithmetic(a, b, func(x, y int) int { return x + y }) },
+  "sub": func(a, b interface{}) (int, error) { return arithmetic(a, b, func(x, y int) int { return x - y }) },
+  "mul": func(a, b interface{}) (int, error) { return arithmetic(a, b, func(x, y int) int { return x * y }) },
+}
+
+// arithmetic applies an operation to two integers, numeric strings such as
+// instance indexes included.
+func arithmetic(a, b interface{}, operation func(x, y int) int) (int, error) {
+  x, err := toInt(a)
+  if err != nil {
+    return 0, err
+  }
+  y, err := toInt(b)
+  if err != nil {
+    return 0, err
+  }
+  return operation(x, y), nil
+}
+
+// toInt converts a template value into an integer.
+func toInt(value interface{}) (int, error) {
+  switch v := value.(type) {
+  case int:
+    return v, nil
+  case int64:
+    return int(v), nil
+  case float64:
+    return int(v), nil
+  case string:
+    return strconv.Atoi(v)
+  }
+  return 0, fmt.Errorf("%v is not a number", value)
+}
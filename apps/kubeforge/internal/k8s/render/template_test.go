@@ -0,0 +1,167 @@
+package render
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	crdv1 "kubeforge/internal/k8s/api/v1"
+)
+
+// nestedLoops ranges three times over a list of 401 items.
+const nestedLoops = `{{ $l := split "," (replace "0" "," (printf "%0400d" 0)) }}` +
+  `{{ range $l }}{{ range $l }}{{ range $l }}{{ end }}{{ end }}{{ end }}`
+
+// templateOverlay returns an Overlay with templates on or off.
+func templateOverlay(templates bool) *crdv1.Overlay {
+  return &crdv1.Overlay{
+    ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+    Spec: crdv1.OverlaySpec{
+      Templates: templates,
+      Values:    &runtime.RawExtension{Raw: []byte(`{"tag":"1.27"}`)},
+    },
+  }
+}
+
+func TestTemplate(t *testing.T) {
+  tests := []struct {
+    name      string
+    templates bool
+    entry     map[string]interface{}
+    want      string
+    wantErr   string
+  }{
+    {
+      name:      "overlay and values",
+      templates: true,
+      entry:     map[string]interface{}{"value": `{{ .Overlay.Name }}:{{ .Values.tag }}`},
+      want:      "shop:1.27",
+    },
+    {
+      name:      "resource name",
+      templates: true,
+      entry: map[string]interface{}{
+        "metadata": map[string]interface{}{"name": "web"},
+        "value":    `{{ .Resource.Name | upper }}`,
+      },
+      want: "WEB",
+    },
+    {
+      name:  "off by default",
+      entry: map[string]interface{}{"value": `{{ .Overlay.Name }}`},
+      want:  `{{ .Overlay.Name }}`,
+    },
+    {
+      name:  "turned on by the entry",
+      entry: map[string]interface{}{templatesKey: true, "value": `{{ .Overlay.Name }}`},
+      want:  "shop",
+    },
+    {
+      name:      "turned off by the entry",
+      templates: true,
+      entry:     map[string]interface{}{templatesKey: false, "value": `{{ .Overlay.Name }}`},
+      want:      `{{ .Overlay.Name }}`,
+    },
+    {
+      name:      "templates key not a boolean",
+      templates: true,
+      entry:     map[string]interface{}{templatesKey: "yes", "value": "a"},
+      wantErr:   "templates must be true or false",
+    },
+    {
+      name:      "missing key",
+      templates: true,
+      entry:     map[string]interface{}{"value": `{{ .Values.missing }}`},
+      wantErr:   `map has no entry for key "missing"`,
+    },
+    {
+      name:      "optional key",
+      templates: true,
+      entry:     map[string]interface{}{"value": `{{ index .Values "missing" | default "stable" }}`},
+      want:      "stable",
+    },
+    {
+      name:      "loops exceeding the step budget",
+      templates: true,
+      entry:     map[string]interface{}{"value": nestedLoops},
+      wantErr:   "templates exceed 100000 loop iterations",
+    },
+    {
+      name:      "loops within the step budget",
+      templates: true,
+      entry:     map[string]interface{}{"value": `{{ range split "," "a,b,c" }}{{ . }}{{ end }}`},
+      want:      "abc",
+    },
+    {
+      name:      "output over the limit",
+      templates: true,
+      entry:     map[string]interface{}{"value": `{{ printf "%070000d" 0 }}`},
+      wantErr:   "output exceeds 65536 bytes",
+    },
+    {
+      name:      "define",
+      templates: true,
+      entry:     map[string]interface{}{"value": `{{ define "x" }}a{{ end }}b`},
+      wantErr:   "templates can not define templates",
+    },
+    {
+      name:      "template",
+      templates: true,
+      entry:     map[string]interface{}{"value": `{{ template "value" . }}`},
+      wantErr:   "templates can not invoke templates",
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      data := map[string]interface{}{"ConfigMap": []interface{}{test.entry}}
+      err := Template(context.Background(), data, templateOverlay(test.templates))
+      if test.wantErr != "" {
+        if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+          t.Fatalf("Template() error = %v, want %q", err, test.wantErr)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("Template() error = %v", err)
+      }
+
+      entry := data["ConfigMap"].([]interface{})[0].(map[string]interface{})
+      if _, exists := entry[templatesKey]; exists {
+        t.Errorf("Template() keeps the %s key", templatesKey)
+      }
+      if entry["value"] != test.want {
+        t.Errorf("Template() value = %q, want %q", entry["value"], test.want)
+      }
+    })
+  }
+}
+
+func TestTemplateBudgetIsShared(t *testing.T) {
+  // Each entry stays within the budget, all of them together do not
+  loop := `{{ $l := split "," (replace "0" "," (printf "%0200d" 0)) }}{{ range $l }}{{ range $l }}{{ end }}{{ end }}`
+  entries := []interface{}{}
+  for index := 0; index < 3; index++ {
+    entries = append(entries, map[string]interface{}{"value": loop})
+  }
+  data := map[string]interface{}{"ConfigMap": entries}
+
+  err := Template(context.Background(), data, templateOverlay(true))
+  if err == nil || !strings.Contains(err.Error(), "loop iterations") {
+    t.Fatalf("Template() error = %v, want the step budget exceeded", err)
+  }
+}
+
+func TestTemplateCanceled(t *testing.T) {
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+
+  data := map[string]interface{}{"ConfigMap": []interface{}{map[string]interface{}{"value": nestedLoops}}}
+  err := Template(ctx, data, templateOverlay(true))
+  if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+    t.Fatalf("Template() error = %v, want %v", err, context.Canceled)
+  }
+}
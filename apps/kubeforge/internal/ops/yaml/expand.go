@@ -0,0 +1,143 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Substitution of environment variables, limited to an allow-list
+// of names and prefixes (`NAME`, `PREFIX_*` or `*` for all):
+//
+//   ${VAR}            the value of VAR, empty when unset
+//   ${VAR:-default}   the value of VAR, default when unset or empty
+//   $$                a literal $
+//
+// Any other `$` is kept as is, a variable outside of the allow-list
+// is an error. A bare `$VAR` of an allowed variable, substituted before
+// `${VAR}` was required, is an error as well rather than silently kept.
+//
+// ############################################################
+
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Expander substitutes the allowed environment variables, a nil Expander
+// substitutes nothing.
+type Expander struct {
+  allowed []string
+  lookup  func(string) (string, bool)
+}
+
+// NewExpander returns an Expander for a comma separated allow-list, nil
+// when the list is empty.
+func NewExpander(allowList string) *Expander {
+  allowed := []string{}
+  for _, entry := range strings.Split(allowList, ",") {
+    if entry = strings.TrimSpace(entry); entry != "" {
+      allowed = append(allowed, entry)
+    }
+  }
+  if len(allowed) == 0 {
+    return nil
+  }
+  return &Expander{allowed: allowed, lookup: os.LookupEnv}
+}
+
+// Allows reports whether a variable is part of the allow-list.
+func (e *Expander) Allows(name string) bool {
+  if e == nil {
+    return false
+  }
+  for _, entry := range e.allowed {
+    if entry == name || (strings.HasSuffix(entry, "*") && strings.HasPrefix(name, strings.TrimSuffix(entry, "*"))) {
+      return true
+    }
+  }
+  return false
+}
+
+// Expand substitutes the variables of a text.
+func (e *Expander) Expand(text string) (string, error) {
+  if e == nil || !strings.Contains(text, "$") {
+    return text, nil
+  }
+
+  var expanded strings.Builder
+  for index := 0; index < len(text); index++ {
+    if text[index] != '$' || index+1 == len(text) {
+      expanded.WriteByte(text[index])
+      continue
+    }
+
+    switch text[index+1] {
+    case '$':
+      expanded.WriteByte('$')
+      index++
+
+    case '{':
+      end := strings.IndexByte(text[index:], '}')
+      if end < 0 {
+        return "", fmt.Errorf("unterminated variable at offset %d", index)
+      }
+      expression := text[index+2 : index+end]
+      value, err := e.value(expression)
+      if err != nil {
+        return "", err
+      }
+      expanded.WriteString(value)
+      index += end
+
+    default:
+      if name := bareVariableName(text[index+1:]); name != "" && e.Allows(name) {
+        return "", fmt.Errorf("bare variable $%s at offset %d, write ${%s} or $$%s for a literal", name, index, name, name)
+      }
+      expanded.WriteByte('$')
+    }
+  }
+  return expanded.String(), nil
+}
+
+// bareVariableName returns the variable name a text starts with.
+func bareVariableName(text string) string {
+  end := 0
+  for end < len(text) && validVariableName(text[:end+1]) {
+    end++
+  }
+  return text[:end]
+}
+
+// value resolves a `VAR` or `VAR:-default` expression.
+func (e *Expander) value(expression string) (string, error) {
+  name, fallback, hasFallback := strings.Cut(expression, ":-")
+  if !validVariableName(name) {
+    return "", fmt.Errorf("invalid variable ${%s}", expression)
+  }
+  if !e.Allows(name) {
+    return "", fmt.Errorf("variable %q is not allowed", name)
+  }
+
+  value, _ := e.lookup(name)
+  if value == "" && hasFallback {
+    return fallback, nil
+  }
+  return value, nil
+}
+
+// validVariableName reports whether a name is a valid variable name.
+func validVariableName(name string) bool {
+  if name == "" {
+    return false
+  }
+  for index, character := range name {
+    switch {
+    case character == '_', character >= 'A' && character <= 'Z', character >= 'a' && character <= 'z':
+    case character >= '0' && character <= '9' && index > 0:
+    default:
+      return false
+    }
+  }
+  return true
+}
@@ -0,0 +1,77 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+)
+
+// testEnvironment is the environment the test expanders look up.
+var testEnvironment = map[string]string{
+  "NAME":         "kubeforge",
+  "EMPTY":        "",
+  "APP_IMAGE":    "busybox",
+  "APP_REPLICAS": "2",
+  "SECRET":       "hunter2",
+}
+
+// testExpander returns an Expander of an allow-list on testEnvironment.
+func testExpander(allowList string) *Expander {
+  expander := NewExpander(allowList)
+  if expander != nil {
+    expander.lookup = func(name string) (string, bool) {
+      value, exists := testEnvironment[name]
+      return value, exists
+    }
+  }
+  return expander
+}
+
+func TestExpand(t *testing.T) {
+  tests := []struct {
+    name      string
+    allowList string
+    text      string
+    want      string
+    wantErr   string
+  }{
+    {name: "variable", allowList: "NAME", text: "name: ${NAME}", want: "name: kubeforge"},
+    {name: "unset variable", allowList: "UNSET", text: "name: ${UNSET}", want: "name: "},
+    {name: "default of an unset variable", allowList: "UNSET", text: "${UNSET:-fallback}", want: "fallback"},
+    {name: "default of an empty variable", allowList: "EMPTY", text: "${EMPTY:-fallback}", want: "fallback"},
+    {name: "default of a set variable", allowList: "NAME", text: "${NAME:-fallback}", want: "kubeforge"},
+    {name: "empty default", allowList: "UNSET", text: "a${UNSET:-}b", want: "ab"},
+    {name: "escaped dollar", allowList: "NAME", text: "$${NAME} $$NAME", want: "${NAME} $NAME"},
+    {name: "other dollars are kept", allowList: "NAME", text: "echo $1 $ $(date) cost$", want: "echo $1 $ $(date) cost$"},
+    {name: "bare variable outside of the allow-list is kept", allowList: "NAME", text: "$SECRET", want: "$SECRET"},
+    {name: "bare allowed variable", allowList: "NAME", text: "name: $NAME", wantErr: "bare variable $NAME at offset 6"},
+    {name: "bare variable of an allowed prefix", allowList: "APP_*", text: "$APP_IMAGE", wantErr: "bare variable $APP_IMAGE"},
+    {name: "prefix", allowList: "APP_*", text: "${APP_IMAGE}:${APP_REPLICAS}", want: "busybox:2"},
+    {name: "prefix does not allow others", allowList: "APP_*", text: "${SECRET}", wantErr: `variable "SECRET" is not allowed`},
+    {name: "all variables", allowList: "*", text: "${SECRET}", want: "hunter2"},
+    {name: "entries are trimmed", allowList: " NAME , APP_* ", text: "${NAME}-${APP_IMAGE}", want: "kubeforge-busybox"},
+    {name: "variable outside of the allow-list", allowList: "NAME", text: "${SECRET}", wantErr: `variable "SECRET" is not allowed`},
+    {name: "unterminated variable", allowList: "NAME", text: "a ${NAME", wantErr: "unterminated variable at offset 2"},
+    {name: "invalid variable", allowList: "*", text: "${1NAME}", wantErr: "invalid variable ${1NAME}"},
+    {name: "empty variable", allowList: "*", text: "${}", wantErr: "invalid variable ${}"},
+    {name: "no allow-list substitutes nothing", allowList: "", text: "${NAME} $NAME", want: "${NAME} $NAME"},
+    {name: "empty allow-list entries", allowList: " , ", text: "${NAME}", want: "${NAME}"},
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      got, err := testExpander(test.allowList).Expand(test.text)
+      if test.wantErr != "" {
+        if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+          t.Fatalf("Expand() error = %v, want %q", err, test.wantErr)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("Expand() error = %v", err)
+      }
+      if got != test.want {
+        t.Errorf("Expand() = %q, want %q", got, test.want)
+      }
+    })
+  }
+}
@@ -0,0 +1,127 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Merge directives of the incoming structure, as known from the
+// strategic merge patch:
+//
+//   $patch: delete                    removes the map, or the list
+//                                     item matching its merge key
+//   $patch: replace                   replaces the map, or the list
+//                                     when it is an item of its own
+//   $retainKeys: [a, b]               keeps only the listed keys
+//   $deleteFromPrimitiveList/<field>  removes values from a list
+//   <field>: null                     removes the field
+//
+// The directives are stripped from the merged structure.
+//
+// ############################################################
+
+package yaml
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	patchDirective                   = "$patch"
+	retainKeysDirective              = "$retainKeys"
+	deleteFromPrimitiveListDirective = "$deleteFromPrimitiveList/"
+	setElementOrderDirective         = "$setElementOrder/"
+)
+
+// patchDirectiveOf returns the $patch directive of a map, if any.
+func patchDirectiveOf(value interface{}) string {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	directive, _ := m[patchDirective].(string)
+	return directive
+}
+
+// isDirectiveKey reports whether a map key is a merge directive.
+func isDirectiveKey(key string) bool {
+	return key == patchDirective ||
+		key == retainKeysDirective ||
+		strings.HasPrefix(key, deleteFromPrimitiveListDirective) ||
+		strings.HasPrefix(key, setElementOrderDirective)
+}
+
+// isReplaceMarker reports whether a list item only asks to replace the list.
+func isReplaceMarker(value interface{}) bool {
+	m, ok := value.(map[string]interface{})
+	return ok && len(m) == 1 && patchDirectiveOf(m) == "replace"
+}
+
+// applyMapDirectives applies the $retainKeys and $deleteFromPrimitiveList
+// directives of the incoming map to the merged one.
+func applyMapDirectives(merged, incoming map[string]interface{}) {
+	for key, value := range incoming {
+		if !strings.HasPrefix(key, deleteFromPrimitiveListDirective) {
+			continue
+		}
+		field := strings.TrimPrefix(key, deleteFromPrimitiveListDirective)
+		values, ok := merged[field].([]interface{})
+		if !ok {
+			continue
+		}
+		deleted, _ := value.([]interface{})
+		merged[field] = deleteFromList(values, deleted)
+	}
+
+	if retainKeys, ok := incoming[retainKeysDirective].([]interface{}); ok {
+		retained := map[string]bool{}
+		for _, key := range retainKeys {
+			retained[fmt.Sprint(key)] = true
+		}
+		for key := range merged {
+			if !retained[key] && !isDirectiveKey(key) {
+				delete(merged, key)
+			}
+		}
+	}
+}
+
+// deleteFromList returns the values not part of the deleted ones.
+func deleteFromList(values, deleted []interface{}) []interface{} {
+	deletedValues := map[string]bool{}
+	for _, value := range deleted {
+		deletedValues[fmt.Sprintf("%#v", value)] = true
+	}
+	kept := []interface{}{}
+	for _, value := range values {
+		if !deletedValues[fmt.Sprintf("%#v", value)] {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+// StripDirectives returns the structure without its merge directives, maps
+// and list items asking for their deletion are dropped.
+func StripDirectives(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		stripped := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if isDirectiveKey(key) || patchDirectiveOf(item) == "delete" {
+				continue
+			}
+			stripped[key] = StripDirectives(item)
+		}
+		return stripped
+	case []interface{}:
+		stripped := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if patchDirectiveOf(item) == "delete" || isReplaceMarker(item) {
+				continue
+			}
+			stripped = append(stripped, StripDirectives(item))
+		}
+		return stripped
+	}
+	return value
+}
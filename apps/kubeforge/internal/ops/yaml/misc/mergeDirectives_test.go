@@ -0,0 +1,136 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   MergeSchema
+		existing interface{}
+		incoming interface{}
+		want     interface{}
+	}{
+		{
+			name:     "null removes the field",
+			existing: map[string]interface{}{"a": 1, "b": 2},
+			incoming: map[string]interface{}{"a": nil},
+			want:     map[string]interface{}{"b": 2},
+		},
+		{
+			name:     "null of a missing field",
+			existing: map[string]interface{}{"b": 2},
+			incoming: map[string]interface{}{"a": nil},
+			want:     map[string]interface{}{"b": 2},
+		},
+		{
+			name:     "$patch: delete removes the map",
+			existing: map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c": 2},
+			incoming: map[string]interface{}{"a": map[string]interface{}{"$patch": "delete"}},
+			want:     map[string]interface{}{"c": 2},
+		},
+		{
+			name:     "$patch: replace replaces the map",
+			existing: map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}},
+			incoming: map[string]interface{}{"a": map[string]interface{}{"$patch": "replace", "d": 3}},
+			want:     map[string]interface{}{"a": map[string]interface{}{"d": 3}},
+		},
+		{
+			name:     "$patch: delete removes the list item matching its name",
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a", "$patch": "delete"}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "b"}}},
+		},
+		{
+			name:     "$patch: delete removes the list item matching its merge key",
+			schema:   testSchema{lists: map[string]testList{"ports": {strategy: ListMerge, keys: []string{"containerPort"}}}},
+			existing: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 80}, map[string]interface{}{"containerPort": 443}}},
+			incoming: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 80, "$patch": "delete"}}},
+			want:     map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 443}}},
+		},
+		{
+			name:     "$patch: delete of a missing list item",
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "b"}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a", "$patch": "delete"}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "b"}}},
+		},
+		{
+			name:     "$patch: replace item replaces the list",
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"$patch": "replace"}, map[string]interface{}{"name": "c"}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "c"}}},
+		},
+		{
+			name:     "$retainKeys keeps the listed keys",
+			existing: map[string]interface{}{"strategy": map[string]interface{}{"type": "RollingUpdate", "rollingUpdate": map[string]interface{}{"maxSurge": 1}}},
+			incoming: map[string]interface{}{"strategy": map[string]interface{}{"$retainKeys": []interface{}{"type"}, "type": "Recreate"}},
+			want:     map[string]interface{}{"strategy": map[string]interface{}{"type": "Recreate"}},
+		},
+		{
+			name:     "$deleteFromPrimitiveList removes values",
+			existing: map[string]interface{}{"finalizers": []interface{}{"a", "b", "c"}},
+			incoming: map[string]interface{}{"$deleteFromPrimitiveList/finalizers": []interface{}{"b", "d"}},
+			want:     map[string]interface{}{"finalizers": []interface{}{"a", "c"}},
+		},
+		{
+			name:     "$deleteFromPrimitiveList of a missing list",
+			existing: map[string]interface{}{"a": 1},
+			incoming: map[string]interface{}{"$deleteFromPrimitiveList/finalizers": []interface{}{"b"}},
+			want:     map[string]interface{}{"a": 1},
+		},
+		{
+			name:     "$setElementOrder is ignored",
+			existing: map[string]interface{}{"items": []interface{}{"a"}},
+			incoming: map[string]interface{}{"$setElementOrder/items": []interface{}{"a"}},
+			want:     map[string]interface{}{"items": []interface{}{"a"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := StripDirectives(StructuresMerge(test.existing, test.incoming, test.schema))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("StructuresMerge() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStripDirectives(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{
+			name:  "directive keys",
+			value: map[string]interface{}{"$patch": "replace", "$retainKeys": []interface{}{"a"}, "$deleteFromPrimitiveList/b": []interface{}{"c"}, "a": 1},
+			want:  map[string]interface{}{"a": 1},
+		},
+		{
+			name:  "deleted maps",
+			value: map[string]interface{}{"a": map[string]interface{}{"$patch": "delete"}, "b": 1},
+			want:  map[string]interface{}{"b": 1},
+		},
+		{
+			name:  "deleted list items and replace markers",
+			value: []interface{}{map[string]interface{}{"$patch": "replace"}, map[string]interface{}{"name": "a", "$patch": "delete"}, map[string]interface{}{"name": "b", "$retainKeys": []interface{}{"name"}}},
+			want:  []interface{}{map[string]interface{}{"name": "b"}},
+		},
+		{
+			name:  "scalars",
+			value: "$patch",
+			want:  "$patch",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := StripDirectives(test.value); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("StripDirectives() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
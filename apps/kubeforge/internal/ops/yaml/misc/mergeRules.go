@@ -0,0 +1,132 @@
+// ############################################################
+// Copyright (c) 2024 wsadza
+// Released under the MIT license
+// ------------------------------------------------------------
+//
+// Merge rules tell how particular lists merge, ahead of the schema
+// and the name heuristics. A rule maps the path of a list, fields
+// separated by dots and list items written as `[]` (e.g.
+// `spec.containers[].ports`), to the key its items are matched by
+// or to a strategy:
+//
+//   merge    items are matched by the key, or by name without key
+//   replace  the incoming list replaces the existing one
+//   append   the incoming items are appended
+//   unique   the union of the values of both lists
+//
+// ############################################################
+
+package yaml
+
+import (
+	"fmt"
+)
+
+// MergeRule is the way a single list merges.
+type MergeRule struct {
+	Key      string
+	Strategy string
+}
+
+// MergeRules maps list paths to their rule.
+type MergeRules map[string]MergeRule
+
+// ParseMergeRules reads the rules of a single kind, a map of paths to either
+// `{key: <field>}` or `{strategy: <strategy>}`.
+func ParseMergeRules(raw interface{}) (MergeRules, error) {
+	rules := MergeRules{}
+	if raw == nil {
+		return rules, nil
+	}
+	paths, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("merge rules must map paths to rules")
+	}
+
+	for path, rawRule := range paths {
+		fields, ok := rawRule.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("merge rule %q must be an object with a key or a strategy", path)
+		}
+		rule := MergeRule{}
+		rule.Key, _ = fields["key"].(string)
+		rule.Strategy, _ = fields["strategy"].(string)
+
+		switch rule.Strategy {
+		case "":
+			if rule.Key == "" {
+				return nil, fmt.Errorf("merge rule %q needs a key or a strategy", path)
+			}
+			rule.Strategy = "merge"
+		case "merge":
+		case "replace", "append", "unique":
+			if rule.Key != "" {
+				return nil, fmt.Errorf("merge rule %q: a key only applies to the merge strategy", path)
+			}
+		default:
+			return nil, fmt.Errorf("merge rule %q: unknown strategy %q, expected merge, replace, append or unique", path, rule.Strategy)
+		}
+		rules[path] = rule
+	}
+	return rules, nil
+}
+
+// WithRules returns the schema with the rules taking precedence, the schema
+// may be nil.
+func WithRules(schema MergeSchema, rules MergeRules) MergeSchema {
+	if len(rules) == 0 {
+		return schema
+	}
+	return rulesMergeSchema{rules: rules, base: schema}
+}
+
+// rulesMergeSchema applies the rules on top of a schema.
+type rulesMergeSchema struct {
+	rules MergeRules
+	base  MergeSchema
+	path  string
+}
+
+func (s rulesMergeSchema) Field(key string) MergeSchema {
+	path := key
+	if s.path != "" {
+		path = s.path + "." + key
+	}
+	return rulesMergeSchema{rules: s.rules, base: fieldSchema(s.base, key), path: path}
+}
+
+func (s rulesMergeSchema) List() (ListStrategy, []string) {
+	rule, exists := s.rules[s.path]
+	if !exists {
+		if s.base == nil {
+			return ListUnknown, nil
+		}
+		return s.base.List()
+	}
+
+	switch rule.Strategy {
+	case "replace":
+		return ListReplace, nil
+	case "append":
+		return ListAppend, nil
+	case "unique":
+		return ListSet, nil
+	}
+	if rule.Key != "" {
+		return ListMerge, []string{rule.Key}
+	}
+	if s.base != nil {
+		if strategy, keys := s.base.List(); strategy == ListMerge {
+			return strategy, keys
+		}
+	}
+	return ListUnknown, nil
+}
+
+func (s rulesMergeSchema) Items() MergeSchema {
+	var base MergeSchema
+	if s.base != nil {
+		base = s.base.Items()
+	}
+	return rulesMergeSchema{rules: s.rules, base: base, path: s.path + "[]"}
+}
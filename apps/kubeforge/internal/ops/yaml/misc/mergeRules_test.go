@@ -0,0 +1,165 @@
+package yaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMergeRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     interface{}
+		want    MergeRules
+		wantErr string
+	}{
+		{
+			name: "no rules",
+			raw:  nil,
+			want: MergeRules{},
+		},
+		{
+			name: "key implies the merge strategy",
+			raw:  map[string]interface{}{"spec.tolerations": map[string]interface{}{"key": "key"}},
+			want: MergeRules{"spec.tolerations": {Key: "key", Strategy: "merge"}},
+		},
+		{
+			name: "strategies",
+			raw: map[string]interface{}{
+				"a": map[string]interface{}{"strategy": "merge"},
+				"b": map[string]interface{}{"strategy": "replace"},
+				"c": map[string]interface{}{"strategy": "append"},
+				"d": map[string]interface{}{"strategy": "unique"},
+			},
+			want: MergeRules{
+				"a": {Strategy: "merge"},
+				"b": {Strategy: "replace"},
+				"c": {Strategy: "append"},
+				"d": {Strategy: "unique"},
+			},
+		},
+		{
+			name:    "not a map",
+			raw:     []interface{}{"spec.tolerations"},
+			wantErr: "must map paths to rules",
+		},
+		{
+			name:    "rule not an object",
+			raw:     map[string]interface{}{"spec.tolerations": "key"},
+			wantErr: "must be an object",
+		},
+		{
+			name:    "neither key nor strategy",
+			raw:     map[string]interface{}{"spec.tolerations": map[string]interface{}{}},
+			wantErr: "needs a key or a strategy",
+		},
+		{
+			name:    "key with another strategy",
+			raw:     map[string]interface{}{"spec.tolerations": map[string]interface{}{"key": "key", "strategy": "append"}},
+			wantErr: "a key only applies to the merge strategy",
+		},
+		{
+			name:    "unknown strategy",
+			raw:     map[string]interface{}{"spec.tolerations": map[string]interface{}{"strategy": "zip"}},
+			wantErr: `unknown strategy "zip"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseMergeRules(test.raw)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ParseMergeRules() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMergeRules() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseMergeRules() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    MergeRules
+		schema   MergeSchema
+		existing interface{}
+		incoming interface{}
+		want     interface{}
+	}{
+		{
+			name:     "merge by key",
+			rules:    MergeRules{"spec.tolerations": {Key: "key", Strategy: "merge"}},
+			existing: map[string]interface{}{"spec": map[string]interface{}{"tolerations": []interface{}{map[string]interface{}{"key": "a", "value": "1"}}}},
+			incoming: map[string]interface{}{"spec": map[string]interface{}{"tolerations": []interface{}{map[string]interface{}{"key": "a", "value": "2"}, map[string]interface{}{"key": "b"}}}},
+			want:     map[string]interface{}{"spec": map[string]interface{}{"tolerations": []interface{}{map[string]interface{}{"key": "a", "value": "2"}, map[string]interface{}{"key": "b"}}}},
+		},
+		{
+			name:     "merge by key of list items",
+			rules:    MergeRules{"spec.containers[].ports": {Key: "containerPort", Strategy: "merge"}},
+			existing: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "a", "ports": []interface{}{map[string]interface{}{"containerPort": 80, "name": "http"}}}}}},
+			incoming: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "a", "ports": []interface{}{map[string]interface{}{"containerPort": 80, "protocol": "TCP"}}}}}},
+			want:     map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "a", "ports": []interface{}{map[string]interface{}{"containerPort": 80, "name": "http", "protocol": "TCP"}}}}}},
+		},
+		{
+			name:     "replace",
+			rules:    MergeRules{"items": {Strategy: "replace"}},
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a", "value": "1"}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a"}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a"}}},
+		},
+		{
+			name:     "append",
+			rules:    MergeRules{"items": {Strategy: "append"}},
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a"}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a"}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "a"}}},
+		},
+		{
+			name:     "unique",
+			rules:    MergeRules{"items": {Strategy: "unique"}},
+			existing: map[string]interface{}{"items": []interface{}{"a", "b"}},
+			incoming: map[string]interface{}{"items": []interface{}{"b", "c"}},
+			want:     map[string]interface{}{"items": []interface{}{"a", "b", "c"}},
+		},
+		{
+			name:     "merge without key keeps the merge keys of the schema",
+			rules:    MergeRules{"items": {Strategy: "merge"}},
+			schema:   testSchema{lists: map[string]testList{"items": {strategy: ListMerge, keys: []string{"id"}}}},
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 1, "value": "a"}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 1, "other": "b"}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 1, "value": "a", "other": "b"}}},
+		},
+		{
+			name:     "rules come before the schema",
+			rules:    MergeRules{"items": {Strategy: "append"}},
+			schema:   testSchema{lists: map[string]testList{"items": {strategy: ListReplace}}},
+			existing: map[string]interface{}{"items": []interface{}{"a"}},
+			incoming: map[string]interface{}{"items": []interface{}{"b"}},
+			want:     map[string]interface{}{"items": []interface{}{"a", "b"}},
+		},
+		{
+			name:     "paths without rule follow the schema",
+			rules:    MergeRules{"other": {Strategy: "append"}},
+			schema:   testSchema{lists: map[string]testList{"items": {strategy: ListReplace}}},
+			existing: map[string]interface{}{"items": []interface{}{"a"}},
+			incoming: map[string]interface{}{"items": []interface{}{"b"}},
+			want:     map[string]interface{}{"items": []interface{}{"b"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := StructuresMerge(test.existing, test.incoming, WithRules(test.schema, test.rules))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("StructuresMerge() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
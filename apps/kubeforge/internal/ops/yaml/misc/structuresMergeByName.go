@@ -3,15 +3,65 @@
 // Released under the MIT license
 // ------------------------------------------------------------
 //
+// Structures are merged the way `kubectl apply` merges them: maps
+// recursively, lists according to the schema of the field. Lists
+// merged by key match their items on the merge keys, sets get the
+// union of their values and any other list known to the schema is
+// replaced. Lists the schema says nothing about match their items
+// by `name` or `metadata.name`. Merge rules of the source
+// configuration come first (see mergeRules.go). The incoming
+// structure may carry merge directives (see mergeDirectives.go).
 //
 // ############################################################
 
 package yaml
 
+import (
+	"fmt"
+	"strings"
+)
+
+// ListStrategy is the way a list is merged.
+type ListStrategy int
+
+const (
+	ListUnknown ListStrategy = iota // not described, items are matched by name
+	ListReplace                     // the incoming list replaces the existing one
+	ListMerge                       // items are matched by their merge keys
+	ListSet                         // the union of the values of both lists
+	ListAppend                      // the incoming items are appended
+)
+
+// MergeSchema describes how the lists of a structure are merged.
+type MergeSchema interface {
+	// Field returns the schema of a field of a map, nil when unknown
+	Field(key string) MergeSchema
+
+	// List returns how the list is merged and the merge keys of its items,
+	// a merge key may be a dotted path (e.g. "metadata.name")
+	List() (ListStrategy, []string)
+
+	// Items returns the schema of the list items, nil when unknown
+	Items() MergeSchema
+}
+
+// StructuresMergeByName merges the incoming structure into the existing one,
+// list items are matched by name.
 func StructuresMergeByName(existing, incoming interface{}) interface{} {
+	return StructuresMerge(existing, incoming, nil)
+}
+
+// StructuresMerge merges the incoming structure into the existing one, the
+// lists are merged according to the schema.
+func StructuresMerge(existing, incoming interface{}, schema MergeSchema) interface{} {
 	switch ex := existing.(type) {
 	case map[string]interface{}:
 		if in, ok := incoming.(map[string]interface{}); ok {
+			// The incoming map replaces the existing one
+			if patchDirectiveOf(in) == "replace" {
+				return in
+			}
+
 			merged := make(map[string]interface{})
 			// Merge maps by iterating over existing and incoming
 			for key, value := range ex {
@@ -19,32 +69,76 @@ func StructuresMergeByName(existing, incoming interface{}) interface{} {
 			}
 			// Traverse over the incoming map
 			for key, value := range in {
+				if isDirectiveKey(key) {
+					continue
+				}
+				// Null and deleted values remove the key
+				if value == nil || patchDirectiveOf(value) == "delete" {
+					delete(merged, key)
+					continue
+				}
 				if existingValue, ok := merged[key]; ok {
 					// Recursively merge values if they exist in both
-					merged[key] = StructuresMergeByName(existingValue, value)
+					merged[key] = StructuresMerge(existingValue, value, fieldSchema(schema, key))
 				} else {
 					merged[key] = value
 				}
 			}
+			applyMapDirectives(merged, in)
 			return merged
 		}
 	case []interface{}:
 		if in, ok := incoming.([]interface{}); ok {
 			// Merge slices recursively
-			return mergeSlices(ex, in)
+			return mergeSlices(ex, in, schema)
 		}
 	}
 	// If the types don't match, return the incoming directly
 	return incoming
 }
 
-func mergeSlices(existing, incoming []interface{}) []interface{} {
+// fieldSchema returns the schema of a field, nil when unknown.
+func fieldSchema(schema MergeSchema, key string) MergeSchema {
+	if schema == nil {
+		return nil
+	}
+	return schema.Field(key)
+}
+
+func mergeSlices(existing, incoming []interface{}, schema MergeSchema) []interface{} {
+	strategy, mergeKeys := ListUnknown, []string(nil)
+	var itemSchema MergeSchema
+	if schema != nil {
+		strategy, mergeKeys = schema.List()
+		itemSchema = schema.Items()
+	}
+
+	// An item asking to replace the list replaces it with the other items
+	for _, incomingItem := range incoming {
+		if isReplaceMarker(incomingItem) {
+			return incoming
+		}
+	}
+
+	switch strategy {
+	case ListReplace:
+		return incoming
+	case ListSet:
+		return mergeSets(existing, incoming)
+	case ListAppend:
+		return append(append([]interface{}{}, existing...), incoming...)
+	}
+
 	// Create a new slice to store the merged result
 	merged := append([]interface{}{}, existing...)
 	existingKeyIndex := map[string]int{} // Track existing items by their keys.
 
-	// Detect key for matching (e.g., "name", "metadata.name", etc.)
-	keyExtractor := identifyKey(existing)
+	// Match the items by their merge keys, or detect the key (e.g., "name",
+	// "metadata.name", etc.) when the schema does not know the list
+	keyExtractor := mergeKeyExtractor(mergeKeys)
+	if strategy == ListUnknown || len(mergeKeys) == 0 {
+		keyExtractor = identifyKey(existing)
+	}
 
 	// Populate existingKeyIndex with indices from the existing slice
 	for i, item := range existing {
@@ -54,13 +148,21 @@ func mergeSlices(existing, incoming []interface{}) []interface{} {
 	}
 
 	// Merge or append items from the incoming slice
+	deleted := map[int]bool{}
 	for _, incomingItem := range incoming {
 		if key := keyExtractor(incomingItem); key != "" {
+			// Items asking for their deletion remove the matching item
+			if patchDirectiveOf(incomingItem) == "delete" {
+				if index, exists := existingKeyIndex[key]; exists {
+					deleted[index] = true
+				}
+				continue
+			}
 			// If the key exists, merge the items recursively
 			if index, exists := existingKeyIndex[key]; exists {
 				existingItem := merged[index]
 				// Merge the matching item from existing and incoming
-				merged[index] = StructuresMergeByName(existingItem, incomingItem)
+				merged[index] = StructuresMerge(existingItem, incomingItem, itemSchema)
 			} else {
 				// If no matching key, add the new item
 				merged = append(merged, incomingItem)
@@ -71,9 +173,71 @@ func mergeSlices(existing, incoming []interface{}) []interface{} {
 		}
 	}
 
+	if len(deleted) == 0 {
+		return merged
+	}
+	kept := make([]interface{}, 0, len(merged)-len(deleted))
+	for index, item := range merged {
+		if !deleted[index] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// mergeSets returns the existing values followed by the incoming values not
+// part of them yet.
+func mergeSets(existing, incoming []interface{}) []interface{} {
+	merged := append([]interface{}{}, existing...)
+	seen := map[string]bool{}
+	for _, item := range existing {
+		seen[fmt.Sprintf("%#v", item)] = true
+	}
+	for _, item := range incoming {
+		if key := fmt.Sprintf("%#v", item); !seen[key] {
+			seen[key] = true
+			merged = append(merged, item)
+		}
+	}
 	return merged
 }
 
+// mergeKeyExtractor returns the key of an item made of its merge keys, an
+// empty key when the item has none of them.
+func mergeKeyExtractor(mergeKeys []string) func(interface{}) string {
+	return func(i interface{}) string {
+		values := make([]string, 0, len(mergeKeys))
+		found := false
+		for _, mergeKey := range mergeKeys {
+			var value interface{} = i
+			for _, field := range strings.Split(mergeKey, ".") {
+				m, ok := value.(map[string]interface{})
+				if !ok {
+					value = nil
+					break
+				}
+				value = m[field]
+			}
+			if value != nil {
+				found = true
+				values = append(values, fmt.Sprint(value))
+			} else {
+				values = append(values, "")
+			}
+		}
+		if !found {
+			return ""
+		}
+		return strings.Join(values, "\x00")
+	}
+}
+
+// MergeKey returns the key of a list item made of its merge keys, empty when
+// the item has none of them.
+func MergeKey(item interface{}, mergeKeys []string) string {
+	return mergeKeyExtractor(mergeKeys)(item)
+}
+
 // IdentifyKey identifies the key used for matching items in a slice.
 // It dynamically detects keys such as "name", "metadata.name", or other common identifiers.
 func identifyKey(slice []interface{}) func(interface{}) string {
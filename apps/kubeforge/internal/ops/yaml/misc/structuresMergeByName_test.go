@@ -0,0 +1,169 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testList is the way a list of testSchema merges.
+type testList struct {
+	strategy ListStrategy
+	keys     []string
+}
+
+// testSchema describes the lists of a structure by their dotted path, list
+// items written as `[]`.
+type testSchema struct {
+	lists map[string]testList
+	path  string
+}
+
+func (s testSchema) Field(key string) MergeSchema {
+	path := key
+	if s.path != "" {
+		path = s.path + "." + key
+	}
+	return testSchema{lists: s.lists, path: path}
+}
+
+func (s testSchema) List() (ListStrategy, []string) {
+	list, exists := s.lists[s.path]
+	if !exists {
+		return ListUnknown, nil
+	}
+	return list.strategy, list.keys
+}
+
+func (s testSchema) Items() MergeSchema {
+	return testSchema{lists: s.lists, path: s.path + "[]"}
+}
+
+func TestStructuresMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   MergeSchema
+		existing interface{}
+		incoming interface{}
+		want     interface{}
+	}{
+		{
+			name:     "maps merge recursively",
+			existing: map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}},
+			incoming: map[string]interface{}{"a": map[string]interface{}{"c": 3, "d": 4}},
+			want:     map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 3, "d": 4}},
+		},
+		{
+			name:     "mismatching types take the incoming value",
+			existing: map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			incoming: map[string]interface{}{"a": "b"},
+			want:     map[string]interface{}{"a": "b"},
+		},
+		{
+			name:     "unknown lists match items by name",
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a", "v": 1}, map[string]interface{}{"name": "b"}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a", "v": 2}, map[string]interface{}{"name": "c"}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a", "v": 2}, map[string]interface{}{"name": "b"}, map[string]interface{}{"name": "c"}}},
+		},
+		{
+			name:     "unknown lists match items by metadata.name",
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "a"}, "v": 1}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "a"}, "v": 2}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "a"}, "v": 2}}},
+		},
+		{
+			name:     "unknown lists without names append the items",
+			existing: map[string]interface{}{"items": []interface{}{"a"}},
+			incoming: map[string]interface{}{"items": []interface{}{"a", "b"}},
+			want:     map[string]interface{}{"items": []interface{}{"a", "a", "b"}},
+		},
+		{
+			name:     "merge keys",
+			schema:   testSchema{lists: map[string]testList{"ports": {strategy: ListMerge, keys: []string{"containerPort"}}}},
+			existing: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 80, "name": "http"}}},
+			incoming: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 80, "protocol": "TCP"}, map[string]interface{}{"containerPort": 443}}},
+			want:     map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 80, "name": "http", "protocol": "TCP"}, map[string]interface{}{"containerPort": 443}}},
+		},
+		{
+			name:     "composite merge keys",
+			schema:   testSchema{lists: map[string]testList{"ports": {strategy: ListMerge, keys: []string{"port", "protocol"}}}},
+			existing: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": 53, "protocol": "UDP", "name": "dns"}}},
+			incoming: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": 53, "protocol": "TCP"}, map[string]interface{}{"port": 53, "protocol": "UDP", "name": "dns-udp"}}},
+			want:     map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": 53, "protocol": "UDP", "name": "dns-udp"}, map[string]interface{}{"port": 53, "protocol": "TCP"}}},
+		},
+		{
+			name:     "dotted merge keys",
+			schema:   testSchema{lists: map[string]testList{"items": {strategy: ListMerge, keys: []string{"metadata.uid"}}}},
+			existing: map[string]interface{}{"items": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"uid": "1"}, "v": 1}}},
+			incoming: map[string]interface{}{"items": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"uid": "1"}, "v": 2}}},
+			want:     map[string]interface{}{"items": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"uid": "1"}, "v": 2}}},
+		},
+		{
+			name:     "merge keyed items are merged with the item schema",
+			schema:   testSchema{lists: map[string]testList{"containers": {strategy: ListMerge, keys: []string{"name"}}, "containers[].args": {strategy: ListReplace}}},
+			existing: map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "a", "args": []interface{}{"x"}}}},
+			incoming: map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "a", "args": []interface{}{"y"}}}},
+			want:     map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "a", "args": []interface{}{"y"}}}},
+		},
+		{
+			name:     "items without merge keys are appended",
+			schema:   testSchema{lists: map[string]testList{"ports": {strategy: ListMerge, keys: []string{"containerPort"}}}},
+			existing: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 80}}},
+			incoming: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"name": "other"}}},
+			want:     map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 80}, map[string]interface{}{"name": "other"}}},
+		},
+		{
+			name:     "sets get the union of their values",
+			schema:   testSchema{lists: map[string]testList{"finalizers": {strategy: ListSet}}},
+			existing: map[string]interface{}{"finalizers": []interface{}{"a", "b"}},
+			incoming: map[string]interface{}{"finalizers": []interface{}{"b", "c", "c"}},
+			want:     map[string]interface{}{"finalizers": []interface{}{"a", "b", "c"}},
+		},
+		{
+			name:     "atomic lists are replaced",
+			schema:   testSchema{lists: map[string]testList{"args": {strategy: ListReplace}}},
+			existing: map[string]interface{}{"args": []interface{}{map[string]interface{}{"name": "a", "v": 1}}},
+			incoming: map[string]interface{}{"args": []interface{}{map[string]interface{}{"name": "a"}}},
+			want:     map[string]interface{}{"args": []interface{}{map[string]interface{}{"name": "a"}}},
+		},
+		{
+			name:     "appended lists",
+			schema:   testSchema{lists: map[string]testList{"items": {strategy: ListAppend}}},
+			existing: map[string]interface{}{"items": []interface{}{"a"}},
+			incoming: map[string]interface{}{"items": []interface{}{"a"}},
+			want:     map[string]interface{}{"items": []interface{}{"a", "a"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := StructuresMerge(test.existing, test.incoming, test.schema)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("StructuresMerge() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeKey(t *testing.T) {
+	tests := []struct {
+		name string
+		item interface{}
+		keys []string
+		want string
+	}{
+		{name: "single key", item: map[string]interface{}{"name": "a"}, keys: []string{"name"}, want: "a"},
+		{name: "numbers", item: map[string]interface{}{"containerPort": 80}, keys: []string{"containerPort"}, want: "80"},
+		{name: "dotted key", item: map[string]interface{}{"metadata": map[string]interface{}{"name": "a"}}, keys: []string{"metadata.name"}, want: "a"},
+		{name: "partial composite key", item: map[string]interface{}{"port": 53}, keys: []string{"port", "protocol"}, want: "53\x00"},
+		{name: "no key", item: map[string]interface{}{"other": "a"}, keys: []string{"name"}, want: ""},
+		{name: "not a map", item: "a", keys: []string{"name"}, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MergeKey(test.item, test.keys); got != test.want {
+				t.Errorf("MergeKey() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -10,12 +10,13 @@ package yaml
 
 import (
 	"fmt"
-	"os"
 	"gopkg.in/yaml.v3"
 	"kubeforge/internal/ops/file"
 )
 
-func Unmarshal(target string, data interface{}, expandData bool) error {
+// Unmarshal unmarshals a file, or the YAML content itself, substituting
+// the variables allowed by the expander (nil substitutes nothing).
+func Unmarshal(target string, data interface{}, expander *Expander) error {
   fileData := []byte(target)
   info, _ := file.Metadata(target)
   if info != nil {
@@ -25,10 +26,11 @@ func Unmarshal(target string, data interface{}, expandData bool) error {
     }
     fileData, _ = fileReaded.([]byte)
   }
-  if expandData {
-    fileData = []byte(os.ExpandEnv(string(fileData)))
+  expandedData, err := expander.Expand(string(fileData))
+  if err != nil {
+    return err
   }
-  return yaml.Unmarshal(fileData, data)
+  return yaml.Unmarshal([]byte(expandedData), data)
 }
 
 func Marshal(data interface{}, yamlData *interface{}) (error) {
@@ -29,6 +29,7 @@ import (
 type KubeforgeV1Interface interface {
 	RESTClient() rest.Interface
 	OverlaysGetter
+	OverlaySourcesGetter
 }
 
 // KubeforgeV1Client is used to interact with features provided by the kubeforge group.
@@ -40,6 +41,10 @@ func (c *KubeforgeV1Client) Overlays(namespace string) OverlayInterface {
 	return newOverlays(c, namespace)
 }
 
+func (c *KubeforgeV1Client) OverlaySources(namespace string) OverlaySourceInterface {
+	return newOverlaySources(c, namespace)
+}
+
 // NewForConfig creates a new KubeforgeV1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -33,6 +33,10 @@ func (c *FakeKubeforgeV1) Overlays(namespace string) v1.OverlayInterface {
 	return &FakeOverlays{c, namespace}
 }
 
+func (c *FakeKubeforgeV1) OverlaySources(namespace string) v1.OverlaySourceInterface {
+	return &FakeOverlaySources{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeKubeforgeV1) RESTClient() rest.Interface {
@@ -0,0 +1,134 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+	v1 "kubeforge/internal/k8s/api/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeOverlaySources implements OverlaySourceInterface
+type FakeOverlaySources struct {
+	Fake *FakeKubeforgeV1
+	ns   string
+}
+
+var overlaysourcesResource = v1.SchemeGroupVersion.WithResource("overlaysources")
+
+var overlaysourcesKind = v1.SchemeGroupVersion.WithKind("OverlaySource")
+
+// Get takes name of the overlaySource, and returns the corresponding overlaySource object, and an error if there is any.
+func (c *FakeOverlaySources) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.OverlaySource, err error) {
+	emptyResult := &v1.OverlaySource{}
+	obj, err := c.Fake.
+		Invokes(testing.NewGetActionWithOptions(overlaysourcesResource, c.ns, name, options), emptyResult)
+
+	if obj == nil {
+		return emptyResult, err
+	}
+	return obj.(*v1.OverlaySource), err
+}
+
+// List takes label and field selectors, and returns the list of OverlaySources that match those selectors.
+func (c *FakeOverlaySources) List(ctx context.Context, opts metav1.ListOptions) (result *v1.OverlaySourceList, err error) {
+	emptyResult := &v1.OverlaySourceList{}
+	obj, err := c.Fake.
+		Invokes(testing.NewListActionWithOptions(overlaysourcesResource, overlaysourcesKind, c.ns, opts), emptyResult)
+
+	if obj == nil {
+		return emptyResult, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.OverlaySourceList{ListMeta: obj.(*v1.OverlaySourceList).ListMeta}
+	for _, item := range obj.(*v1.OverlaySourceList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested overlaySources.
+func (c *FakeOverlaySources) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchActionWithOptions(overlaysourcesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a overlaySource and creates it.  Returns the server's representation of the overlaySource, and an error, if there is any.
+func (c *FakeOverlaySources) Create(ctx context.Context, overlaySource *v1.OverlaySource, opts metav1.CreateOptions) (result *v1.OverlaySource, err error) {
+	emptyResult := &v1.OverlaySource{}
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateActionWithOptions(overlaysourcesResource, c.ns, overlaySource, opts), emptyResult)
+
+	if obj == nil {
+		return emptyResult, err
+	}
+	return obj.(*v1.OverlaySource), err
+}
+
+// Update takes the representation of a overlaySource and updates it. Returns the server's representation of the overlaySource, and an error, if there is any.
+func (c *FakeOverlaySources) Update(ctx context.Context, overlaySource *v1.OverlaySource, opts metav1.UpdateOptions) (result *v1.OverlaySource, err error) {
+	emptyResult := &v1.OverlaySource{}
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateActionWithOptions(overlaysourcesResource, c.ns, overlaySource, opts), emptyResult)
+
+	if obj == nil {
+		return emptyResult, err
+	}
+	return obj.(*v1.OverlaySource), err
+}
+
+// Delete takes name of the overlaySource and deletes it. Returns an error if one occurs.
+func (c *FakeOverlaySources) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(overlaysourcesResource, c.ns, name, opts), &v1.OverlaySource{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeOverlaySources) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	action := testing.NewDeleteCollectionActionWithOptions(overlaysourcesResource, c.ns, opts, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1.OverlaySourceList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched overlaySource.
+func (c *FakeOverlaySources) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.OverlaySource, err error) {
+	emptyResult := &v1.OverlaySource{}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceActionWithOptions(overlaysourcesResource, c.ns, name, pt, data, opts, subresources...), emptyResult)
+
+	if obj == nil {
+		return emptyResult, err
+	}
+	return obj.(*v1.OverlaySource), err
+}
@@ -0,0 +1,67 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	v1 "kubeforge/internal/k8s/api/v1"
+	scheme "kubeforge/pkg/generated/clientset/versioned/scheme"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// OverlaySourcesGetter has a method to return a OverlaySourceInterface.
+// A group's client should implement this interface.
+type OverlaySourcesGetter interface {
+	OverlaySources(namespace string) OverlaySourceInterface
+}
+
+// OverlaySourceInterface has methods to work with OverlaySource resources.
+type OverlaySourceInterface interface {
+	Create(ctx context.Context, overlaySource *v1.OverlaySource, opts metav1.CreateOptions) (*v1.OverlaySource, error)
+	Update(ctx context.Context, overlaySource *v1.OverlaySource, opts metav1.UpdateOptions) (*v1.OverlaySource, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.OverlaySource, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.OverlaySourceList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.OverlaySource, err error)
+	OverlaySourceExpansion
+}
+
+// overlaySources implements OverlaySourceInterface
+type overlaySources struct {
+	*gentype.ClientWithList[*v1.OverlaySource, *v1.OverlaySourceList]
+}
+
+// newOverlaySources returns a OverlaySources
+func newOverlaySources(c *KubeforgeV1Client, namespace string) *overlaySources {
+	return &overlaySources{
+		gentype.NewClientWithList[*v1.OverlaySource, *v1.OverlaySourceList](
+			"overlaysources",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			namespace,
+			func() *v1.OverlaySource { return &v1.OverlaySource{} },
+			func() *v1.OverlaySourceList { return &v1.OverlaySourceList{} }),
+	}
+}
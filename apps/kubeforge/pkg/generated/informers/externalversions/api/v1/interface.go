@@ -26,6 +26,8 @@ import (
 type Interface interface {
 	// Overlays returns a OverlayInformer.
 	Overlays() OverlayInformer
+	// OverlaySources returns a OverlaySourceInformer.
+	OverlaySources() OverlaySourceInformer
 }
 
 type version struct {
@@ -43,3 +45,8 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) Overlays() OverlayInformer {
 	return &overlayInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// OverlaySources returns a OverlaySourceInformer.
+func (v *version) OverlaySources() OverlaySourceInformer {
+	return &overlaySourceInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
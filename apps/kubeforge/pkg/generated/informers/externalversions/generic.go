@@ -55,6 +55,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=kubeforge, Version=v1
 	case v1.SchemeGroupVersion.WithResource("overlays"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubeforge().V1().Overlays().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("overlaysources"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubeforge().V1().OverlaySources().Informer()}, nil
 
 	}
 
@@ -25,3 +25,11 @@ type OverlayListerExpansion interface{}
 // OverlayNamespaceListerExpansion allows custom methods to be added to
 // OverlayNamespaceLister.
 type OverlayNamespaceListerExpansion interface{}
+
+// OverlaySourceListerExpansion allows custom methods to be added to
+// OverlaySourceLister.
+type OverlaySourceListerExpansion interface{}
+
+// OverlaySourceNamespaceListerExpansion allows custom methods to be added to
+// OverlaySourceNamespaceLister.
+type OverlaySourceNamespaceListerExpansion interface{}
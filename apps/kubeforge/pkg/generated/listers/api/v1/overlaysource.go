@@ -0,0 +1,71 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	v1 "kubeforge/internal/k8s/api/v1"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/listers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// OverlaySourceLister helps list OverlaySources.
+// All objects returned here must be treated as read-only.
+type OverlaySourceLister interface {
+	// List lists all OverlaySources in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1.OverlaySource, err error)
+	// OverlaySources returns an object that can list and get OverlaySources.
+	OverlaySources(namespace string) OverlaySourceNamespaceLister
+	OverlaySourceListerExpansion
+}
+
+// overlaySourceLister implements the OverlaySourceLister interface.
+type overlaySourceLister struct {
+	listers.ResourceIndexer[*v1.OverlaySource]
+}
+
+// NewOverlaySourceLister returns a new OverlaySourceLister.
+func NewOverlaySourceLister(indexer cache.Indexer) OverlaySourceLister {
+	return &overlaySourceLister{listers.New[*v1.OverlaySource](indexer, v1.Resource("overlaysource"))}
+}
+
+// OverlaySources returns an object that can list and get OverlaySources.
+func (s *overlaySourceLister) OverlaySources(namespace string) OverlaySourceNamespaceLister {
+	return overlaySourceNamespaceLister{listers.NewNamespaced[*v1.OverlaySource](s.ResourceIndexer, namespace)}
+}
+
+// OverlaySourceNamespaceLister helps list and get OverlaySources.
+// All objects returned here must be treated as read-only.
+type OverlaySourceNamespaceLister interface {
+	// List lists all OverlaySources in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1.OverlaySource, err error)
+	// Get retrieves the OverlaySource from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1.OverlaySource, error)
+	OverlaySourceNamespaceListerExpansion
+}
+
+// overlaySourceNamespaceLister implements the OverlaySourceNamespaceLister
+// interface.
+type overlaySourceNamespaceLister struct {
+	listers.ResourceIndexer[*v1.OverlaySource]
+}